@@ -4,21 +4,64 @@ import (
 	"ai/api/pb"
 	"ai/internal/usecase"
 	"context"
+	"crypto/subtle"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type aiServer struct {
 	usecase usecase.AiUsecase
+	// adminToken guards GetHealth, which is disabled while it is empty.
+	adminToken string
 	pb.UnimplementedAiServiceServer
 }
 
-func NewAiServer(usecase usecase.AiUsecase) pb.AiServiceServer {
-	return &aiServer{usecase: usecase}
+func NewAiServer(usecase usecase.AiUsecase, adminToken string) pb.AiServiceServer {
+	return &aiServer{usecase: usecase, adminToken: adminToken}
 }
 
 func (s *aiServer) Ask(ctx context.Context, req *pb.AiRequest) (*pb.AiResponse, error) {
-	res, err := s.usecase.Ask(req)
+	res, err := s.usecase.Ask(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, toStatus(err)
 	}
 	return res, nil
 }
+
+func (s *aiServer) AskStream(req *pb.AiRequest, stream pb.AiService_AskStreamServer) error {
+	return toStatus(s.usecase.AskStream(stream.Context(), req, stream.Send))
+}
+
+// toStatus reports running out of time as DeadlineExceeded and a caller
+// that went away as Canceled.
+func toStatus(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return status.FromContextError(err).Err()
+	}
+	return err
+}
+
+func (s *aiServer) GetHealth(ctx context.Context, req *pb.HealthRequest) (*pb.HealthResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.usecase.GetHealth(ctx)
+}
+
+// authorizeAdmin checks the "authorization: Bearer <token>" metadata of an
+// admin call against adminToken.
+func (s *aiServer) authorizeAdmin(ctx context.Context) error {
+	if s.adminToken == "" {
+		return status.Error(codes.PermissionDenied, "admin calls are disabled")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if subtle.ConstantTimeCompare([]byte(v), []byte("Bearer "+s.adminToken)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid admin token")
+}
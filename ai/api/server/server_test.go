@@ -0,0 +1,28 @@
+package server_test
+
+import (
+	"ai/api/pb"
+	"ai/api/server"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestGetHealthNeedsAdminToken(t *testing.T) {
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	}
+
+	_, err := server.NewAiServer(nil, "").GetHealth(withToken(""), &pb.HealthRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	s := server.NewAiServer(nil, "secret")
+	_, err = s.GetHealth(context.Background(), &pb.HealthRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = s.GetHealth(withToken("guess"), &pb.HealthRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -1,6 +1,7 @@
 package infrastructure
 
 import (
+	"ai/api/pb"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // ANSI color codes
@@ -42,7 +44,15 @@ func LogResponsesInterceptor(
 
 	// Log the request payload
 	fmt.Println(colorYellow + "Request Payload:" + colorReset)
-	logJSON(req)
+	if r, ok := req.(*pb.AiRequest); ok && len(r.Image) > 0 {
+		// don't dump uploaded photos into the log
+		withoutImage := proto.Clone(r).(*pb.AiRequest)
+		withoutImage.Image = nil
+		fmt.Printf("Image: %d bytes\n", len(r.Image))
+		logJSON(withoutImage)
+	} else {
+		logJSON(req)
+	}
 
 	// Call the handler to get the response or error
 	res, err := handler(ctx, req)
@@ -71,3 +81,29 @@ func logJSON(v interface{}) {
 	}
 	fmt.Println(string(jsonData))
 }
+
+// LogStreamInterceptor logs the start and end of server-streaming gRPC calls.
+func LogStreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+
+	fmt.Println(colorCyan + "===== Incoming gRPC Stream =====" + colorReset)
+	fmt.Printf(colorBlue+"Method: %s\n"+colorReset, info.FullMethod)
+
+	err := handler(srv, ss)
+
+	if err != nil {
+		grpcErr, _ := status.FromError(err)
+		fmt.Println(colorRed + "===== gRPC Stream Error =====" + colorReset)
+		fmt.Printf("Error Code: %s\n", grpcErr.Code())
+		fmt.Printf("Error Message: %s\n", grpcErr.Message())
+	} else {
+		fmt.Println(colorGreen + "===== gRPC Stream Closed =====" + colorReset)
+	}
+
+	fmt.Println(colorCyan + "===============================" + colorReset)
+	return err
+}
@@ -3,14 +3,29 @@ package infrastructure
 import (
 	"ai/api/pb"
 	"ai/api/server"
+	"ai/internal/embedding"
 	"ai/internal/entity"
+	"ai/internal/grounding"
+	"ai/internal/health"
+	"ai/internal/keypool"
+	"ai/internal/prompt"
+	"ai/internal/provider"
+	"ai/internal/provider/anthropic"
+	"ai/internal/provider/gemini"
+	"ai/internal/provider/openai"
 	"ai/internal/repository"
+	"ai/internal/retrieval"
+	"ai/internal/retry"
 	"ai/internal/usecase"
+	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"time"
 
 	"net"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
 	"gorm.io/gorm"
@@ -18,40 +33,63 @@ import (
 
 type Resources struct {
 	*gorm.DB
-	gemini   entity.Ai
-	chatgpt  entity.Ai
-	claude   entity.Ai
-	deepSeek entity.Ai
+	ais map[string]entity.Ai
+	// openAICompatible lists the configured providers that speak the OpenAI
+	// chat completions wire format.
+	openAICompatible []string
+}
+
+// openAIBaseURLs are used when a section does not set its own base_url.
+var openAIBaseURLs = map[string]string{
+	"chatgpt":  "https://api.openai.com/v1",
+	"deepseek": "https://api.deepseek.com/v1",
 }
 
 func NewServer(version, buildTag, runEnv string) (servers *Resources, err error) {
 	var resources Resources
+	resources.ais = make(map[string]entity.Ai)
 	if geminiKey := getKeyOrModel("gemini", "keys"); len(geminiKey) != 0 {
 		if geminiModel := getKeyOrModel("gemini", "models"); len(geminiModel) != 0 {
-			resources.gemini.Keys = geminiKey
-			resources.gemini.Models = geminiModel
+			resources.ais["gemini"] = entity.Ai{Keys: geminiKey, Models: geminiModel}
+		}
+	}
+	if chatGPTKey := getKeyOrModel("chatgpt", "keys"); len(chatGPTKey) != 0 {
+		if chatGPTModel := getKeyOrModel("chatgpt", "models"); len(chatGPTModel) != 0 {
+			resources.ais["chatgpt"] = entity.Ai{Keys: chatGPTKey, Models: chatGPTModel, BaseURL: getBaseURL("chatgpt")}
+			resources.openAICompatible = append(resources.openAICompatible, "chatgpt")
+		}
+	}
+	if claudeKey := getKeyOrModel("claude", "keys"); len(claudeKey) != 0 {
+		if claudeModel := getKeyOrModel("claude", "models"); len(claudeModel) != 0 {
+			resources.ais["claude"] = entity.Ai{Keys: claudeKey, Models: claudeModel, BaseURL: viper.GetString("claude.base_url")}
+		}
+	}
+	if deepSeekKey := getKeyOrModel("deepseek", "keys"); len(deepSeekKey) != 0 {
+		if deepSeekModel := getKeyOrModel("deepseek", "models"); len(deepSeekModel) != 0 {
+			resources.ais["deepseek"] = entity.Ai{Keys: deepSeekKey, Models: deepSeekModel, BaseURL: getBaseURL("deepseek")}
+			resources.openAICompatible = append(resources.openAICompatible, "deepseek")
+		}
+	}
+	// Self-hosted servers are listed by section name under openai_compatible,
+	// e.g. openai_compatible: ["ollama"] with ollama.base_url and ollama.models.
+	// Keys are optional for them.
+	for _, name := range viper.GetStringSlice("openai_compatible") {
+		if _, ok := resources.ais[name]; ok {
+			continue
+		}
+		if model := getKeyOrModel(name, "models"); len(model) != 0 && getBaseURL(name) != "" {
+			resources.ais[name] = entity.Ai{Keys: getKeyOrModel(name, "keys"), Models: model, BaseURL: getBaseURL(name)}
+			resources.openAICompatible = append(resources.openAICompatible, name)
 		}
 	}
-	// if chatGPTKey := getKeyOrModel("chatgpt", "keys"); len(chatGPTKey) != 0 {
-	// 	if chatGPTModel := getKeyOrModel("chatgpt", "models"); len(chatGPTModel) != 0 {
-	// 		resources.chatgpt.Keys = chatGPTKey
-	// 		resources.chatgpt.Models = chatGPTModel
-	// 	}
-	// }
-	// if claudeKey := getKeyOrModel("claude", "keys"); len(claudeKey) != 0 {
-	// 	if claudeModel := getKeyOrModel("claude", "models"); len(claudeModel) != 0 {
-	// 		resources.claude.Keys = claudeKey
-	// 		resources.claude.Models = claudeModel
-	// 	}
-	// }
-	// if deepSeekKey := getKeyOrModel("deepseek", "keys"); len(deepSeekKey) != 0 {
-	// 	if deepSeekModel := getKeyOrModel("deepseek", "models"); len(deepSeekModel) != 0 {
-	// 		resources.deepSeek.Keys = deepSeekKey
-	// 		resources.deepSeek.Models = deepSeekModel
-	// 	}
-	// }
 	return &resources, nil
 }
+func getBaseURL(which string) string {
+	if baseURL := viper.GetString(which + ".base_url"); baseURL != "" {
+		return baseURL
+	}
+	return openAIBaseURLs[which]
+}
 func getKeyOrModel(whichKeyOrModel string, keyOrModel string) []string {
 	var keys []string
 	i := 1
@@ -66,6 +104,103 @@ func getKeyOrModel(whichKeyOrModel string, keyOrModel string) []string {
 	return keys
 }
 
+// newHealthRegistry shares model and key cooldowns through Redis when
+// health.redis.addr is set, otherwise they live in this process only.
+func newHealthRegistry() *health.Registry {
+	config := health.Config{
+		ModelCooldown: viper.GetDuration("health.model_cooldown"),
+		KeyCooldown:   viper.GetDuration("health.key_cooldown"),
+	}
+	if addr := viper.GetString("health.redis.addr"); addr != "" {
+		client := redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Password: viper.GetString("health.redis.password"),
+			DB:       viper.GetInt("health.redis.db"),
+		})
+		return health.NewRegistry(health.NewRedisStore(client), config)
+	}
+	return health.NewRegistry(health.NewMemoryStore(), config)
+}
+
+// newRetriever searches the knowledge base by keywords, and by meaning too
+// when ai.embedding.provider is gemini, openai or hash. The vectors are kept
+// in ai.embedding.path so only changed entries are embedded after a restart.
+func (s *Resources) newRetriever(httpClient *http.Client) *retrieval.Retriever {
+	config := retrieval.Config{
+		Dir:  viper.GetString("ai.knowledge.path"),
+		TopK: viper.GetInt("ai.retrieval.top_k"),
+		// ai.faq.threshold above 1 always asks a model
+		FAQThreshold: viper.GetFloat64("ai.faq.threshold"),
+	}
+	var embedder embedding.Embedder
+	switch name := viper.GetString("ai.embedding.provider"); name {
+	case "":
+	case "gemini":
+		embedder = embedding.NewGemini(s.embeddingKey("gemini"), viper.GetString("ai.embedding.model"))
+	case "openai":
+		baseURL := viper.GetString("ai.embedding.base_url")
+		if baseURL == "" {
+			baseURL = getBaseURL("chatgpt")
+		}
+		embedder = embedding.NewOpenAI(embedding.OpenAIConfig{
+			BaseURL:    baseURL,
+			APIKey:     s.embeddingKey("chatgpt"),
+			Model:      viper.GetString("ai.embedding.model"),
+			HTTPClient: httpClient,
+		})
+	case "hash":
+		embedder = embedding.NewHash(viper.GetInt("ai.embedding.dim"))
+	default:
+		log.Printf("Unknown embedding provider %q, searching by keywords only", name)
+	}
+	if embedder == nil {
+		return retrieval.NewRetriever(config)
+	}
+	path := viper.GetString("ai.embedding.path")
+	if path == "" {
+		path = "./data/embeddings.json"
+	}
+	store, err := embedding.OpenStore(path, embedder)
+	if err != nil {
+		log.Printf("Failed to open the vector store, searching by keywords only: %v", err)
+		return retrieval.NewRetriever(config)
+	}
+	config.Vectors = store
+	return retrieval.NewRetriever(config)
+}
+
+// embeddingKey is ai.embedding.api_key, or the first key of the provider.
+func (s *Resources) embeddingKey(ai string) string {
+	if key := viper.GetString("ai.embedding.api_key"); key != "" {
+		return key
+	}
+	if keys := s.ais[ai].Keys; len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// newRetryPolicy reads ai.retry: max_attempts, retries of the same model,
+// base_delay and max_delay of the backoff, and actions by error class, e.g.
+// actions: {invalid: cool_down}.
+func newRetryPolicy() retry.Policy {
+	policy := retry.Policy{
+		MaxAttempts: viper.GetInt("ai.retry.max_attempts"),
+		Retries:     viper.GetInt("ai.retry.retries"),
+		BaseDelay:   viper.GetDuration("ai.retry.base_delay"),
+		MaxDelay:    viper.GetDuration("ai.retry.max_delay"),
+		Actions:     map[retry.Class]retry.Action{},
+	}
+	for class, name := range viper.GetStringMapString("ai.retry.actions") {
+		action, err := retry.ParseAction(name)
+		if err != nil {
+			log.Fatalf("Failed to read ai.retry.actions: %v", err)
+		}
+		policy.Actions[retry.Class(class)] = action
+	}
+	return policy
+}
+
 func (s *Resources) Run() {
 	AutoMigrate(s.DB)
 	// Start GRPC Server
@@ -73,10 +208,100 @@ func (s *Resources) Run() {
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
-	aiRepository := repository.NewAiRepository(s.chatgpt, s.claude, s.gemini, s.deepSeek)
+	providers := provider.NewRegistry()
+	if err := providers.Register(gemini.New(gemini.Config{})); err != nil {
+		log.Fatalf("Failed to register provider: %v", err)
+	}
+	httpClient := &http.Client{Timeout: 2 * time.Minute}
+	if _, ok := s.ais["claude"]; ok {
+		if err := providers.Register(anthropic.New(anthropic.Config{
+			BaseURL:    s.ais["claude"].BaseURL,
+			MaxTokens:  viper.GetInt("claude.max_tokens"),
+			HTTPClient: httpClient,
+		})); err != nil {
+			log.Fatalf("Failed to register provider: %v", err)
+		}
+	}
+	for _, name := range s.openAICompatible {
+		modalities := []provider.Modality{provider.Text}
+		if viper.GetBool(name + ".vision") {
+			modalities = append(modalities, provider.Image)
+		}
+		// <provider>.json_mode: false for servers that reject response_format
+		jsonMode := true
+		if viper.IsSet(name + ".json_mode") {
+			jsonMode = viper.GetBool(name + ".json_mode")
+		}
+		if err := providers.Register(openai.New(openai.Config{
+			Name:       name,
+			BaseURL:    s.ais[name].BaseURL,
+			Modalities: modalities,
+			JSONMode:   jsonMode,
+			HTTPClient: httpClient,
+		})); err != nil {
+			log.Fatalf("Failed to register provider: %v", err)
+		}
+	}
+	// ai.fallback sets the provider priority, e.g. [gemini, deepseek, chatgpt];
+	// by default providers are tried in the order they are registered.
+	// ai.key_strategy is weighted (default) or round_robin; <provider>.daily_quota
+	// caps the requests per key per day.
+	keys := keypool.New(keypool.Strategy(viper.GetString("ai.key_strategy")))
+	for name, ai := range s.ais {
+		keys.Add(name, ai.Keys, viper.GetInt64(name+".daily_quota"))
+	}
+	// the knowledge is loaded once and reloaded when a file in ai.knowledge.path changes
+	knowledge := s.newRetriever(httpClient)
+	if err := knowledge.Reload(context.Background()); err != nil {
+		log.Printf("Failed to load knowledge: %v", err)
+	}
+	if err := knowledge.Watch(context.Background()); err != nil {
+		log.Printf("Failed to watch knowledge: %v", err)
+	}
+	// prompts are text templates in ai.prompt.path, ai.prompt.version pins a
+	// version, the last one is used otherwise; they are reloaded when changed
+	prompts := prompt.NewSet(prompt.Config{
+		Dir:     viper.GetString("ai.prompt.path"),
+		Version: viper.GetString("ai.prompt.version"),
+	})
+	if err := prompts.Reload(); err != nil {
+		log.Fatalf("Failed to load prompts: %v", err)
+	}
+	if err := prompts.Watch(context.Background()); err != nil {
+		log.Printf("Failed to watch prompts: %v", err)
+	}
+	// ai.unknown.escalation lists how answers the model did not know are
+	// followed up, in order: search, provider and handoff; search by default
+	escalation := []string{entity.EscalationSearch}
+	if viper.IsSet("ai.unknown.escalation") {
+		escalation = viper.GetStringSlice("ai.unknown.escalation")
+	}
+	// grounded search asks ai.grounding.model, gemini-2.0-flash by default,
+	// waiting at most ai.grounding.timeout
+	search := grounding.New(grounding.Config{
+		BaseURL:    viper.GetString("ai.grounding.base_url"),
+		Model:      viper.GetString("ai.grounding.model"),
+		Timeout:    viper.GetDuration("ai.grounding.timeout"),
+		HTTPClient: httpClient,
+	})
+	// ai.timeout.attempt bounds one call to a model, ai.timeout.total a whole
+	// question, never past the caller's deadline less ai.timeout.reserve
+	timeouts := repository.Timeouts{
+		Attempt: viper.GetDuration("ai.timeout.attempt"),
+		Total:   viper.GetDuration("ai.timeout.total"),
+		Reserve: viper.GetDuration("ai.timeout.reserve"),
+	}
+	aiRepository := repository.NewAiRepository(providers, s.ais, viper.GetStringSlice("ai.fallback"), newHealthRegistry(), keys, viper.GetInt("ai.history.token_budget"), knowledge, prompts, search, escalation, timeouts, newRetryPolicy())
 	aiUsecase := usecase.NewAiService(aiRepository)
-	aiServer := server.NewAiServer(aiUsecase)
-	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(LogResponsesInterceptor))
+	// GetHealth needs "authorization: Bearer <admin.token>" and is off
+	// without a token; it is meant for operators, not the gateway
+	aiServer := server.NewAiServer(aiUsecase, viper.GetString("admin.token"))
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(LogResponsesInterceptor),
+		grpc.StreamInterceptor(LogStreamInterceptor),
+		// questions may carry a photo, the 4MB default is too tight
+		grpc.MaxRecvMsgSize(16*1024*1024),
+	)
 	pb.RegisterAiServiceServer(grpcServer, aiServer)
 	log.Println("Server started on port :", viper.GetInt("grpc.port"))
 	err = grpcServer.Serve(lis)
@@ -0,0 +1,120 @@
+// Package bilingual splits an answer written in Thai and English into one
+// text per language, and joins the two back into the labelled form answers
+// have always been returned in.
+package bilingual
+
+import (
+	"ai/internal/knowledge"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Answer holds the same answer in Thai and in English. Either is empty when
+// the answer was only asked for in the other language.
+type Answer struct {
+	TH string `json:"th"`
+	EN string `json:"en"`
+}
+
+// Labels of the languages in a text answer.
+const (
+	ThaiLabel    = "ภาษาไทย:"
+	EnglishLabel = "English:"
+)
+
+// label finds a language label, also when models put it in bold or write
+// it in the other language, e.g. "**English:**" or "ภาษาอังกฤษ:".
+var label = regexp.MustCompile(`(?i)[*_#]*\s*(ภาษาไทย|ไทย|thai|ภาษาอังกฤษ|อังกฤษ|english)\s*[*_]*\s*[:：]\s*[*_]*`)
+
+// Parse reads the answer of a model: a JSON object like {"th": "...", "en":
+// "..."}, also inside a code fence, or text labelled "ภาษาไทย:" and
+// "English:". Text without labels is taken to be in the language of its
+// script.
+func Parse(text string) Answer {
+	if a, ok := parseJSON(text); ok {
+		return a
+	}
+	return parseText(text)
+}
+
+func parseJSON(text string) (Answer, bool) {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return Answer{}, false
+	}
+	var a Answer
+	if err := json.Unmarshal([]byte(text[start:end+1]), &a); err != nil || (a.TH == "" && a.EN == "") {
+		return Answer{}, false
+	}
+	return Answer{TH: strings.TrimSpace(a.TH), EN: strings.TrimSpace(a.EN)}, true
+}
+
+func parseText(text string) Answer {
+	matches := label.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		text = strings.TrimSpace(text)
+		if isThai(text) {
+			return Answer{TH: text}
+		}
+		return Answer{EN: text}
+	}
+	var th, en []string
+	for i, m := range matches {
+		end := len(text)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		part := strings.TrimSpace(text[m[1]:end])
+		if part == "" {
+			continue
+		}
+		switch strings.ToLower(text[m[2]:m[3]]) {
+		case "ภาษาไทย", "ไทย", "thai":
+			th = append(th, part)
+		default:
+			en = append(en, part)
+		}
+	}
+	return Answer{TH: strings.Join(th, "\n"), EN: strings.Join(en, "\n")}
+}
+
+// isThai reports whether text has more Thai letters than Latin ones.
+func isThai(text string) bool {
+	thai, latin := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+	return thai > latin
+}
+
+// String is the answer in the labelled form, or the text of the only
+// language it has.
+func (a Answer) String() string {
+	switch {
+	case a.TH == "":
+		return a.EN
+	case a.EN == "":
+		return a.TH
+	}
+	return ThaiLabel + " " + a.TH + "\n" + EnglishLabel + " " + a.EN
+}
+
+// In keeps only lang, knowledge.Thai or knowledge.English. Any other lang
+// keeps both. When the answer lacks lang it is kept as it is rather than
+// dropped.
+func (a Answer) In(lang string) Answer {
+	switch {
+	case lang == knowledge.Thai && a.TH != "":
+		return Answer{TH: a.TH}
+	case lang == knowledge.English && a.EN != "":
+		return Answer{EN: a.EN}
+	}
+	return a
+}
@@ -0,0 +1,72 @@
+package bilingual_test
+
+import (
+	"ai/internal/bilingual"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want bilingual.Answer
+	}{
+		{
+			name: "labelled",
+			text: "ภาษาไทย: อยู่ข้างตึก 50\nEnglish: Near 50th anniversary building",
+			want: bilingual.Answer{TH: "อยู่ข้างตึก 50", EN: "Near 50th anniversary building"},
+		},
+		{
+			name: "bold labels on one line",
+			text: "**ภาษาไทย:** อยู่ข้างตึก 50 **English:** Near 50th anniversary building (question.json#campus-1)",
+			want: bilingual.Answer{TH: "อยู่ข้างตึก 50", EN: "Near 50th anniversary building (question.json#campus-1)"},
+		},
+		{
+			name: "labels written in Thai",
+			text: "ไทย: ยื่นเอกสาร\nภาษาอังกฤษ: Submit the documents",
+			want: bilingual.Answer{TH: "ยื่นเอกสาร", EN: "Submit the documents"},
+		},
+		{
+			name: "json",
+			text: `{"th": "ยื่นเอกสาร", "en": "Submit the documents"}`,
+			want: bilingual.Answer{TH: "ยื่นเอกสาร", EN: "Submit the documents"},
+		},
+		{
+			name: "json in a code fence",
+			text: "```json\n{\"th\": \"ยื่นเอกสาร\",\n \"en\": \"Submit the documents\"}\n```",
+			want: bilingual.Answer{TH: "ยื่นเอกสาร", EN: "Submit the documents"},
+		},
+		{
+			name: "unlabelled Thai",
+			text: "ยื่นเอกสารที่บัณฑิตวิทยาลัย GS",
+			want: bilingual.Answer{TH: "ยื่นเอกสารที่บัณฑิตวิทยาลัย GS"},
+		},
+		{
+			name: "unlabelled English",
+			text: "Don't know",
+			want: bilingual.Answer{EN: "Don't know"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, bilingual.Parse(c.text))
+		})
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	a := bilingual.Answer{TH: "ยื่นเอกสาร", EN: "Submit the documents"}
+	t.Run("string", func(t *testing.T) {
+		assert.Equal(t, "ภาษาไทย: ยื่นเอกสาร\nEnglish: Submit the documents", a.String())
+		assert.Equal(t, bilingual.Parse(a.String()), a)
+	})
+	t.Run("in", func(t *testing.T) {
+		assert.Equal(t, "ยื่นเอกสาร", a.In("th").String())
+		assert.Equal(t, "Submit the documents", a.In("en").String())
+		assert.Equal(t, a, a.In(""))
+		// a Thai only answer is not emptied when English is asked for
+		assert.Equal(t, "ยื่นเอกสาร", bilingual.Answer{TH: "ยื่นเอกสาร"}.In("en").String())
+	})
+}
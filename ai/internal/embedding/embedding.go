@@ -0,0 +1,42 @@
+package embedding
+
+import (
+	"context"
+	"math"
+)
+
+// Kind tells the embedder what a text is used for. Some models embed search
+// queries and the documents they are matched against differently.
+type Kind int
+
+const (
+	Document Kind = iota
+	Query
+)
+
+// Embedder turns texts into vectors whose cosine similarity reflects how
+// close their meaning is.
+type Embedder interface {
+	// Name identifies the embedder and model. Vectors of different names are
+	// never compared.
+	Name() string
+	Embed(ctx context.Context, kind Kind, texts []string) ([][]float32, error)
+}
+
+// Cosine returns the cosine similarity of a and b, 0 when their lengths differ
+// or either is zero.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
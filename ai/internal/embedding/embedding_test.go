@@ -0,0 +1,107 @@
+package embedding_test
+
+import (
+	"ai/internal/embedding"
+	"ai/internal/retrieval"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// counting wraps an embedder and counts the texts it embeds.
+type counting struct {
+	embedding.Embedder
+	texts int
+}
+
+func (c *counting) Embed(ctx context.Context, kind embedding.Kind, texts []string) ([][]float32, error) {
+	c.texts += len(texts)
+	return c.Embedder.Embed(ctx, kind, texts)
+}
+
+var chunks = []retrieval.Chunk{
+	{Source: "question.json", Title: "การลาพักการศึกษาจะต้องดำเนินการอย่างไรบ้าง", Text: "ยื่นคำร้องผ่านระบบ"},
+	{Source: "question.json", Title: "การขอสำเร็จการศึกษา จะต้องดำเนินการอย่างไรบ้าง", Text: "ยื่นเอกสาร"},
+	{Source: "parking.md", Text: "Parking is behind building 50."},
+}
+
+func TestHash(t *testing.T) {
+	h := embedding.NewHash(64)
+	a, err := h.Embed(context.Background(), embedding.Document, []string{"ลาพักการศึกษา", "ลาพักการศึกษา", "parking"})
+	assert.NoError(t, err)
+	assert.Len(t, a[0], 64)
+	assert.Equal(t, a[0], a[1])
+	assert.InDelta(t, 1, embedding.Cosine(a[0], a[1]), 1e-6)
+	assert.Less(t, embedding.Cosine(a[0], a[2]), 0.5)
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "embeddings.json")
+	embedder := &counting{Embedder: embedding.NewHash(256)}
+	store, err := embedding.OpenStore(path, embedder)
+	assert.NoError(t, err)
+
+	t.Run("search", func(t *testing.T) {
+		assert.NoError(t, store.Sync(context.Background(), chunks))
+		assert.Equal(t, 3, embedder.texts)
+		results, err := store.Search(context.Background(), "ลาพักการศึกษาทำยังไง", 1)
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, chunks[0], results[0].Chunk)
+	})
+	t.Run("unchanged chunks are not embedded again", func(t *testing.T) {
+		reopened, err := embedding.OpenStore(path, embedder)
+		assert.NoError(t, err)
+		embedder.texts = 0
+		changed := append([]retrieval.Chunk{}, chunks...)
+		changed[2].Text = "Parking is next to building 50."
+		assert.NoError(t, reopened.Sync(context.Background(), changed))
+		assert.Equal(t, 1, embedder.texts)
+	})
+	t.Run("other embedder starts empty", func(t *testing.T) {
+		other := &counting{Embedder: embedding.NewHash(128)}
+		reopened, err := embedding.OpenStore(path, other)
+		assert.NoError(t, err)
+		assert.NoError(t, reopened.Sync(context.Background(), chunks))
+		assert.Equal(t, 3, other.texts)
+	})
+}
+
+func TestOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "text-embedding-3-small", req.Model)
+		if req.Input[0] == "fail" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"quota"}}`)
+			return
+		}
+		// answered out of order on purpose
+		fmt.Fprint(w, `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`)
+	}))
+	defer server.Close()
+
+	e := embedding.NewOpenAI(embedding.OpenAIConfig{BaseURL: server.URL + "/v1", APIKey: "sk-test"})
+
+	t.Run("success", func(t *testing.T) {
+		vectors, err := e.Embed(context.Background(), embedding.Query, []string{"a", "b"})
+		assert.NoError(t, err)
+		assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors)
+	})
+	t.Run("error", func(t *testing.T) {
+		_, err := e.Embed(context.Background(), embedding.Query, []string{"fail"})
+		assert.ErrorContains(t, err, "429")
+	})
+}
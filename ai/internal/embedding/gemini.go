@@ -0,0 +1,63 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// geminiBatch is the most texts one BatchEmbedContents call accepts.
+const geminiBatch = 100
+
+type gemini struct {
+	apiKey string
+	model  string
+}
+
+// NewGemini embeds with a Gemini embedding model, text-embedding-004 when
+// model is empty.
+func NewGemini(apiKey string, model string) Embedder {
+	if model == "" {
+		model = "text-embedding-004"
+	}
+	return &gemini{apiKey, model}
+}
+
+func (g *gemini) Name() string {
+	return "gemini/" + g.model
+}
+
+func (g *gemini) Embed(ctx context.Context, kind Kind, texts []string) ([][]float32, error) {
+	client, err := genai.NewClient(ctx, option.WithAPIKey(g.apiKey))
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	em := client.EmbeddingModel(g.model)
+	em.TaskType = genai.TaskTypeRetrievalDocument
+	if kind == Query {
+		em.TaskType = genai.TaskTypeRetrievalQuery
+	}
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += geminiBatch {
+		end := min(start+geminiBatch, len(texts))
+		batch := em.NewBatch()
+		for _, t := range texts[start:end] {
+			batch.AddContent(genai.Text(t))
+		}
+		resp, err := em.BatchEmbedContents(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Embeddings) != end-start {
+			return nil, fmt.Errorf("gemini: got %d embeddings for %d texts", len(resp.Embeddings), end-start)
+		}
+		for _, e := range resp.Embeddings {
+			vectors = append(vectors, e.Values)
+		}
+	}
+	return vectors, nil
+}
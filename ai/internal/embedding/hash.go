@@ -0,0 +1,63 @@
+package embedding
+
+import (
+	"ai/internal/retrieval"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+type hash struct {
+	dim int
+}
+
+// NewHash returns a local embedder that hashes the segmented terms of a text
+// into dim buckets. It needs no network and always gives the same vector for
+// the same text, which makes it useful for tests and offline development. It
+// only matches shared words, not meaning.
+func NewHash(dim int) Embedder {
+	if dim <= 0 {
+		dim = 256
+	}
+	return &hash{dim}
+}
+
+func (h *hash) Name() string {
+	return fmt.Sprintf("hash/%d", h.dim)
+}
+
+func (h *hash) Embed(ctx context.Context, kind Kind, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, t := range texts {
+		v := make([]float32, h.dim)
+		for _, term := range retrieval.Segment(t) {
+			f := fnv.New64a()
+			f.Write([]byte(term))
+			sum := f.Sum64()
+			// the top bit picks the sign so collisions tend to cancel out
+			if sum>>63 == 1 {
+				v[sum%uint64(h.dim)]--
+			} else {
+				v[sum%uint64(h.dim)]++
+			}
+		}
+		normalize(v)
+		vectors[i] = v
+	}
+	return vectors, nil
+}
+
+func normalize(v []float32) {
+	var n float64
+	for _, x := range v {
+		n += float64(x) * float64(x)
+	}
+	if n == 0 {
+		return
+	}
+	n = math.Sqrt(n)
+	for i := range v {
+		v[i] = float32(float64(v[i]) / n)
+	}
+}
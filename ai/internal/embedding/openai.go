@@ -0,0 +1,94 @@
+package embedding
+
+import (
+	"ai/internal/provider"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type OpenAIConfig struct {
+	BaseURL string
+	APIKey  string
+	// Model defaults to text-embedding-3-small.
+	Model      string
+	HTTPClient *http.Client
+}
+
+type openAI struct {
+	config OpenAIConfig
+}
+
+// NewOpenAI embeds with the /embeddings endpoint of OpenAI or a compatible
+// server.
+func NewOpenAI(config OpenAIConfig) Embedder {
+	if config.Model == "" {
+		config.Model = "text-embedding-3-small"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	return &openAI{config}
+}
+
+func (o *openAI) Name() string {
+	return "openai/" + o.config.Model
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (o *openAI) Embed(ctx context.Context, kind Kind, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{Model: o.config.Model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.config.BaseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if o.config.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+o.config.APIKey)
+	}
+	resp, err := o.config.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, provider.NewStatusError(o.Name(), resp, data)
+	}
+	var res embeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range res.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("%s: embedding index %d out of range", o.Name(), d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	for i, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("%s: no embedding for text %d", o.Name(), i)
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,160 @@
+package embedding
+
+import (
+	"ai/internal/retrieval"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// storeBatch is the number of chunks embedded per call while syncing.
+const storeBatch = 100
+
+type entry struct {
+	// Hash is the hash of the embedded text, used to skip unchanged chunks.
+	Hash   string          `json:"hash"`
+	Chunk  retrieval.Chunk `json:"chunk"`
+	Vector []float32       `json:"vector"`
+}
+
+type storeFile struct {
+	Embedder string  `json:"embedder"`
+	Entries  []entry `json:"entries"`
+}
+
+// Store is a vector index of knowledge chunks saved as a JSON file, so only
+// new or changed chunks are embedded after a restart.
+type Store struct {
+	path     string
+	embedder Embedder
+	mu       sync.RWMutex
+	entries  []entry
+}
+
+// OpenStore loads the vectors saved at path. A missing file or one written
+// by another embedder gives an empty store.
+func OpenStore(path string, embedder Embedder) (*Store, error) {
+	s := &Store{path: path, embedder: embedder}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if file.Embedder == embedder.Name() {
+		s.entries = file.Entries
+	}
+	return s, nil
+}
+
+func chunkText(c retrieval.Chunk) string {
+	if c.Title == "" || c.Title == c.Text {
+		return c.Text
+	}
+	return c.Title + "\n" + c.Text
+}
+
+func hashOf(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sync makes the store hold exactly chunks. Vectors of unchanged chunks are
+// kept, the rest are embedded, and the result is saved.
+func (s *Store) Sync(ctx context.Context, chunks []retrieval.Chunk) error {
+	s.mu.RLock()
+	known := make(map[string][]float32, len(s.entries))
+	for _, e := range s.entries {
+		known[e.Hash] = e.Vector
+	}
+	s.mu.RUnlock()
+
+	entries := make([]entry, len(chunks))
+	var missing []int
+	for i, c := range chunks {
+		h := hashOf(chunkText(c))
+		entries[i] = entry{Hash: h, Chunk: c, Vector: known[h]}
+		if entries[i].Vector == nil {
+			missing = append(missing, i)
+		}
+	}
+	for start := 0; start < len(missing); start += storeBatch {
+		batch := missing[start:min(start+storeBatch, len(missing))]
+		texts := make([]string, len(batch))
+		for j, i := range batch {
+			texts[j] = chunkText(chunks[i])
+		}
+		vectors, err := s.embedder.Embed(ctx, Document, texts)
+		if err != nil {
+			return err
+		}
+		for j, i := range batch {
+			entries[i].Vector = vectors[j]
+		}
+	}
+
+	s.mu.Lock()
+	s.entries = entries
+	s.mu.Unlock()
+	if len(missing) == 0 && len(known) == len(entries) {
+		return nil
+	}
+	return s.save(entries)
+}
+
+// save writes to a temporary file first so a crash never leaves a torn file.
+func (s *Store) save(entries []entry) error {
+	data, err := json.Marshal(storeFile{Embedder: s.embedder.Name(), Entries: entries})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Search returns the k chunks closest to query by cosine similarity.
+func (s *Store) Search(ctx context.Context, query string, k int) ([]retrieval.Result, error) {
+	vectors, err := s.embedder.Embed(ctx, Query, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	results := make([]retrieval.Result, 0, len(s.entries))
+	now := time.Now()
+	for _, e := range s.entries {
+		if !e.Chunk.EffectiveAt(now) {
+			continue
+		}
+		score := Cosine(vectors[0], e.Vector)
+		if score > 0 {
+			results = append(results, retrieval.Result{Chunk: e.Chunk, Score: score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
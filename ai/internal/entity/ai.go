@@ -1,14 +1,105 @@
 package entity
 
+import "time"
+
 type AiRequest struct {
-	Question string
-	Image    []byte
+	Question      string
+	Image         []byte
+	ImageMimeType string
+	// History holds the earlier turns of the conversation, oldest first.
+	History []Message
+	// Lang is th or en to answer in that language only, empty for both.
+	Lang string
+}
+
+// Message is one turn of a conversation, Role is either user or assistant.
+type Message struct {
+	Role    string
+	Content string
 }
+
+// Sources of an answer.
+const (
+	SourceFAQ    = "faq"
+	SourceModel  = "model"
+	SourceSearch = "search"
+	// SourceHandoff is the notice that staff will answer the question.
+	SourceHandoff = "handoff"
+)
+
+// Outcomes of a question; the model tells which one it gave.
+const (
+	OutcomeAnswered = "answered"
+	OutcomeUnknown  = "unknown"
+	OutcomeRefused  = "refused"
+)
+
+// Escalations of an unknown answer, tried in the order of
+// ai.unknown.escalation.
+const (
+	// EscalationSearch asks Gemini again with Google Search.
+	EscalationSearch = "search"
+	// EscalationProvider asks the next provider of the chain.
+	EscalationProvider = "provider"
+	// EscalationHandoff passes the question to staff.
+	EscalationHandoff = "handoff"
+)
+
 type AiAnswer struct {
-	Answer string
-	Image  []byte
+	// Answer is labelled "ภาษาไทย: ... English: ..." when it has both
+	// languages, AnswerTH and AnswerEN hold them apart.
+	Answer   string
+	AnswerTH string
+	AnswerEN string
+	Source   string
+	// Outcome is answered, unknown or refused.
+	Outcome string
+	// Escalation is how an unknown answer was followed up, empty when it
+	// was not.
+	Escalation string
+	// KnowledgeVersion is the version of the knowledge base in use.
+	KnowledgeVersion string
+	// PromptVersion tells which prompt templates produced the answer, e.g.
+	// v1 or v1/claude; empty for FAQ answers.
+	PromptVersion string
+	// Citations are the sources that back the answer.
+	Citations []Citation
+	// Attempts are the model calls made for the answer, in order.
+	Attempts []Attempt
+	Image    []byte
+	// Provider and Model gave the answer.
+	Provider     string
+	Model        string
+	FinishReason string
+	InputTokens  int
+	OutputTokens int
+}
+
+// Citation is a knowledge base entry, a page of a document or, with URL
+// set, a web page.
+type Citation struct {
+	Source  string
+	EntryID string
+	Page    int
+	Title   string
+	URL     string
 }
+
+// Attempt is one call to a model. Class and Action are set when it failed,
+// see package retry.
+type Attempt struct {
+	Provider string
+	Model    string
+	// Key is a fingerprint of the API key.
+	Key      string
+	Duration time.Duration
+	Error    string
+	Class    string
+	Action   string
+}
+
 type Ai struct {
-	Keys   []string
-	Models []string
+	Keys    []string
+	Models  []string
+	BaseURL string
 }
@@ -0,0 +1,189 @@
+// Package grounding asks Gemini through its REST API with Google Search as
+// a tool, so answers come with the web pages they were found on.
+package grounding
+
+import (
+	"ai/internal/provider"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	// BaseURL defaults to https://generativelanguage.googleapis.com/v1beta.
+	BaseURL string
+	// Model defaults to gemini-2.0-flash.
+	Model string
+	// Timeout bounds one call, one minute by default.
+	Timeout    time.Duration
+	HTTPClient *http.Client
+}
+
+type Client struct {
+	config Config
+}
+
+func New(config Config) *Client {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	}
+	if config.Model == "" {
+		config.Model = "gemini-2.0-flash"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = time.Minute
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	return &Client{config}
+}
+
+// Model is the model asked.
+func (c *Client) Model() string {
+	return c.config.Model
+}
+
+type Request struct {
+	Contents []Content `json:"contents"`
+	Tools    []Tool    `json:"tools,omitempty"`
+}
+
+type Content struct {
+	// Role is user or model, empty for a single question.
+	Role  string `json:"role,omitempty"`
+	Parts []Part `json:"parts"`
+}
+
+type Part struct {
+	Text string `json:"text"`
+}
+
+type Tool struct {
+	GoogleSearch *GoogleSearch `json:"google_search,omitempty"`
+}
+
+// GoogleSearch has no options, its presence turns the search on.
+type GoogleSearch struct{}
+
+type Response struct {
+	Candidates    []Candidate    `json:"candidates"`
+	UsageMetadata *UsageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string         `json:"modelVersion,omitempty"`
+}
+
+type Candidate struct {
+	Content           Content            `json:"content"`
+	FinishReason      string             `json:"finishReason,omitempty"`
+	GroundingMetadata *GroundingMetadata `json:"groundingMetadata,omitempty"`
+}
+
+// GroundingMetadata tells what was searched for and which pages were used.
+type GroundingMetadata struct {
+	WebSearchQueries []string         `json:"webSearchQueries,omitempty"`
+	GroundingChunks  []GroundingChunk `json:"groundingChunks,omitempty"`
+}
+
+type GroundingChunk struct {
+	Web *Web `json:"web,omitempty"`
+}
+
+// Web is a page an answer was found on. URI is usually a redirect through
+// Google, Title the domain of the page.
+type Web struct {
+	URI   string `json:"uri"`
+	Title string `json:"title,omitempty"`
+}
+
+type UsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// Search asks prompt with Google Search enabled.
+func (c *Client) Search(ctx context.Context, apiKey string, prompt string) (*Response, error) {
+	return c.Generate(ctx, apiKey, &Request{
+		Contents: []Content{{Parts: []Part{{Text: prompt}}}},
+		Tools:    []Tool{{GoogleSearch: &GoogleSearch{}}},
+	})
+}
+
+// Generate calls generateContent. A non-200 response is returned as a
+// provider.StatusError so quota errors can be told apart.
+func (c *Client) Generate(ctx context.Context, apiKey string, req *Request) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	url := fmt.Sprintf("%s/models/%s:generateContent", c.config.BaseURL, c.config.Model)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", apiKey)
+	resp, err := c.config.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, provider.NewStatusError("gemini", resp, data)
+	}
+	var res Response
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	if len(res.Candidates) == 0 {
+		return nil, fmt.Errorf("no candidates found")
+	}
+	return &res, nil
+}
+
+// Text joins the text parts of the first candidate by lines.
+func (r *Response) Text() string {
+	if len(r.Candidates) == 0 {
+		return ""
+	}
+	var texts []string
+	for _, p := range r.Candidates[0].Content.Parts {
+		texts = append(texts, p.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Sources are the pages the first candidate was found on, each once.
+func (r *Response) Sources() []Web {
+	if len(r.Candidates) == 0 || r.Candidates[0].GroundingMetadata == nil {
+		return nil
+	}
+	var sources []Web
+	seen := map[string]bool{}
+	for _, c := range r.Candidates[0].GroundingMetadata.GroundingChunks {
+		if c.Web == nil || c.Web.URI == "" || seen[c.Web.URI] {
+			continue
+		}
+		seen[c.Web.URI] = true
+		sources = append(sources, *c.Web)
+	}
+	return sources
+}
+
+// Queries are what Google was searched for.
+func (r *Response) Queries() []string {
+	if len(r.Candidates) == 0 || r.Candidates[0].GroundingMetadata == nil {
+		return nil
+	}
+	return r.Candidates[0].GroundingMetadata.WebSearchQueries
+}
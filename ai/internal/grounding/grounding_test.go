@@ -0,0 +1,102 @@
+package grounding_test
+
+import (
+	"ai/internal/grounding"
+	"ai/internal/provider"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const searchResponse = `{
+  "candidates": [{
+    "content": {"role": "model", "parts": [{"text": "ภาษาไทย: อยู่ข้างตึก 50"}, {"text": "English: Near 50th anniversary building"}]},
+    "finishReason": "STOP",
+    "groundingMetadata": {
+      "webSearchQueries": ["EN16101 KKU"],
+      "groundingChunks": [
+        {"web": {"uri": "https://vertexaisearch.cloud.google.com/grounding-api-redirect/a", "title": "kku.ac.th"}},
+        {"web": {"uri": "https://vertexaisearch.cloud.google.com/grounding-api-redirect/a", "title": "kku.ac.th"}},
+        {"web": {"uri": "https://vertexaisearch.cloud.google.com/grounding-api-redirect/b", "title": "en.kku.ac.th"}}
+      ]
+    }
+  }],
+  "usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 30, "totalTokenCount": 42},
+  "modelVersion": "gemini-2.0-flash"
+}`
+
+func TestSearch(t *testing.T) {
+	var got grounding.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models/gemini-2.5-flash:generateContent", r.URL.Path)
+		assert.Empty(t, r.URL.RawQuery)
+		assert.Equal(t, "key", r.Header.Get("x-goog-api-key"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(searchResponse))
+	}))
+	defer server.Close()
+
+	client := grounding.New(grounding.Config{BaseURL: server.URL + "/", Model: "gemini-2.5-flash"})
+	resp, err := client.Search(context.Background(), "key", "Where is EN16101?")
+	assert.NoError(t, err)
+	assert.Equal(t, "Where is EN16101?", got.Contents[0].Parts[0].Text)
+	assert.NotNil(t, got.Tools[0].GoogleSearch)
+
+	assert.Equal(t, "ภาษาไทย: อยู่ข้างตึก 50\nEnglish: Near 50th anniversary building", resp.Text())
+	assert.Equal(t, []string{"EN16101 KKU"}, resp.Queries())
+	assert.Equal(t, []grounding.Web{
+		{URI: "https://vertexaisearch.cloud.google.com/grounding-api-redirect/a", Title: "kku.ac.th"},
+		{URI: "https://vertexaisearch.cloud.google.com/grounding-api-redirect/b", Title: "en.kku.ac.th"},
+	}, resp.Sources())
+	assert.Equal(t, "STOP", resp.Candidates[0].FinishReason)
+	assert.Equal(t, 42, resp.UsageMetadata.TotalTokenCount)
+}
+
+func TestErrors(t *testing.T) {
+	t.Run("status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "30")
+			http.Error(w, `{"error": {"status": "RESOURCE_EXHAUSTED"}}`, http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		_, err := grounding.New(grounding.Config{BaseURL: server.URL}).Search(context.Background(), "key", "q")
+		var statusErr *provider.StatusError
+		assert.True(t, errors.As(err, &statusErr))
+		assert.Equal(t, http.StatusTooManyRequests, statusErr.StatusCode)
+		assert.Equal(t, 30*time.Second, statusErr.RetryAfter)
+	})
+	t.Run("no candidates", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"candidates": []}`))
+		}))
+		defer server.Close()
+
+		_, err := grounding.New(grounding.Config{BaseURL: server.URL}).Search(context.Background(), "key", "q")
+		assert.Error(t, err)
+	})
+	t.Run("timeout", func(t *testing.T) {
+		done := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		}))
+		defer server.Close()
+		defer close(done)
+
+		_, err := grounding.New(grounding.Config{BaseURL: server.URL, Timeout: 50 * time.Millisecond}).Search(context.Background(), "key", "q")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := grounding.New(grounding.Config{BaseURL: "http://127.0.0.1:1"}).Search(ctx, "key", "q")
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,219 @@
+package health
+
+import (
+	"ai/internal/provider"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/grpc/codes"
+)
+
+// maxReasonLen is how many bytes of the error a cooldown keeps.
+const maxReasonLen = 200
+
+// Cooldown is a model or an API key that is skipped until Until.
+// Key holds a fingerprint of the API key, never the key itself.
+type Cooldown struct {
+	Provider string    `json:"provider"`
+	Model    string    `json:"model,omitempty"`
+	Key      string    `json:"key,omitempty"`
+	Until    time.Time `json:"until"`
+	Reason   string    `json:"reason"`
+}
+
+// Store keeps cooldowns by id until they expire.
+type Store interface {
+	Put(ctx context.Context, id string, cooldown Cooldown) error
+	Get(ctx context.Context, id string) (*Cooldown, error)
+	List(ctx context.Context) ([]Cooldown, error)
+}
+
+type Config struct {
+	// ModelCooldown is used when a model fails without saying when to retry.
+	ModelCooldown time.Duration
+	// KeyCooldown is used for rejected or exhausted keys without a retry hint.
+	KeyCooldown time.Duration
+}
+
+// Registry is the process wide view of which models and keys are usable.
+// It is shared by all requests, and across replicas when the store is.
+type Registry struct {
+	store  Store
+	config Config
+}
+
+func NewRegistry(store Store, config Config) *Registry {
+	if config.ModelCooldown == 0 {
+		config.ModelCooldown = 30 * time.Second
+	}
+	if config.KeyCooldown == 0 {
+		config.KeyCooldown = 10 * time.Minute
+	}
+	return &Registry{store, config}
+}
+
+func modelID(ai string, model string) string {
+	return "model:" + ai + "/" + model
+}
+
+func keyID(ai string, key string) string {
+	return "key:" + ai + "/" + Fingerprint(key)
+}
+
+// Fingerprint identifies an API key in logs and admin output without
+// revealing it.
+func Fingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+func (r *Registry) available(ctx context.Context, id string) bool {
+	cooldown, err := r.store.Get(ctx, id)
+	if err != nil {
+		// an unreachable store must not take every model down with it
+		fmt.Println(err)
+		return true
+	}
+	return cooldown == nil || time.Now().After(cooldown.Until)
+}
+
+func (r *Registry) ModelAvailable(ctx context.Context, ai string, model string) bool {
+	return r.available(ctx, modelID(ai, model))
+}
+
+func (r *Registry) KeyAvailable(ctx context.Context, ai string, key string) bool {
+	return r.available(ctx, keyID(ai, key))
+}
+
+// Report records a failed call of model with key. Rejected and exhausted keys
+// are cooled down on their own so the other keys keep serving the model;
+// any other failure cools down the model. It returns the cooldown it set.
+func (r *Registry) Report(ctx context.Context, ai string, model string, key string, err error) Cooldown {
+	cooldown := Cooldown{Provider: ai, Reason: err.Error()}
+	var id string
+	fallback := r.config.ModelCooldown
+	if IsKeyError(err) {
+		cooldown.Key = Fingerprint(key)
+		id = keyID(ai, key)
+		fallback = r.config.KeyCooldown
+	} else {
+		cooldown.Model = model
+		id = modelID(ai, model)
+	}
+	wait, ok := RetryAfter(err)
+	if !ok {
+		wait = fallback
+	}
+	cooldown.Until = time.Now().Add(wait)
+	cooldown.Reason = truncate(cooldown.Reason, maxReasonLen)
+	if err := r.store.Put(ctx, id, cooldown); err != nil {
+		fmt.Println(err)
+	}
+	return cooldown
+}
+
+// truncate cuts s to at most n bytes on a rune boundary and drops invalid
+// UTF-8, which gRPC refuses to marshal in a string.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		s = s[:n]
+	}
+	return strings.ToValidUTF8(s, "")
+}
+
+// List returns the active cooldowns, soonest to expire first.
+func (r *Registry) List(ctx context.Context) ([]Cooldown, error) {
+	cooldowns, err := r.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	active := cooldowns[:0]
+	for _, c := range cooldowns {
+		if time.Now().Before(c.Until) {
+			active = append(active, c)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Until.Before(active[j].Until) })
+	return active, nil
+}
+
+// StatusCode returns the HTTP status of a provider error, zero when unknown.
+func StatusCode(err error) int {
+	var statusErr *provider.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPCode()
+	}
+	return 0
+}
+
+// IsKeyError reports whether err blames the API key rather than the model:
+// it was rejected, or its quota is used up.
+func IsKeyError(err error) bool {
+	switch StatusCode(err) {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "api key not valid") ||
+		strings.Contains(msg, "invalid x-api-key") ||
+		IsQuotaError(err)
+}
+
+// IsQuotaError reports whether err says the key ran out of quota or hit a
+// rate limit: a 429 status or a RESOURCE_EXHAUSTED gRPC code. Other text
+// merely mentioning a quota does not count.
+func IsQuotaError(err error) bool {
+	if StatusCode(err) == http.StatusTooManyRequests {
+		return true
+	}
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) && apiErr.GRPCStatus().Code() == codes.ResourceExhausted {
+		return true
+	}
+	return strings.Contains(err.Error(), "RESOURCE_EXHAUSTED")
+}
+
+// retryInMessage matches hints such as "Please retry in 27.5s" and
+// "retryDelay": "27s" that providers put in quota errors.
+var retryInMessage = regexp.MustCompile(`(?i)(?:retry in|retrydelay"?:\s*"?)\s*([0-9]+(?:\.[0-9]+)?)\s*s`)
+
+// RetryAfter extracts how long the provider asked us to wait, from a
+// Retry-After header, a google.rpc.RetryInfo detail or the error text.
+func RetryAfter(err error) (time.Duration, bool) {
+	var statusErr *provider.StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		return statusErr.RetryAfter, true
+	}
+	var apiErr *apierror.APIError
+	if errors.As(err, &apiErr) {
+		if info := apiErr.Details().RetryInfo; info != nil && info.GetRetryDelay() != nil {
+			if d := info.GetRetryDelay().AsDuration(); d > 0 {
+				return d, true
+			}
+		}
+	}
+	if m := retryInMessage.FindStringSubmatch(err.Error()); m != nil {
+		if seconds, err := strconv.ParseFloat(m[1], 64); err == nil && seconds > 0 {
+			return time.Duration(seconds * float64(time.Second)), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,126 @@
+package health_test
+
+import (
+	"ai/internal/health"
+	"ai/internal/provider"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/googleapis/gax-go/v2/apierror"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func statusError(code int, retryAfter string, body string) error {
+	resp := &http.Response{StatusCode: code, Header: http.Header{}}
+	if retryAfter != "" {
+		resp.Header.Set("Retry-After", retryAfter)
+	}
+	return provider.NewStatusError("chatgpt", resp, []byte(body))
+}
+
+func grpcError(t *testing.T, code codes.Code, msg string, retryDelay time.Duration) error {
+	st := status.New(code, msg)
+	if retryDelay > 0 {
+		var err error
+		st, err = st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryDelay)})
+		assert.NoError(t, err)
+	}
+	apiErr, ok := apierror.FromError(st.Err())
+	assert.True(t, ok)
+	return apiErr
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want time.Duration
+		ok   bool
+	}{
+		{"Retry-After header", statusError(http.StatusTooManyRequests, "12", ""), 12 * time.Second, true},
+		{"Retry-After header with fractions", statusError(http.StatusTooManyRequests, "1.5", ""), 1500 * time.Millisecond, true},
+		{"invalid Retry-After header", statusError(http.StatusTooManyRequests, "soon", ""), 0, false},
+		{"RetryInfo", grpcError(t, codes.ResourceExhausted, "quota exceeded", 27*time.Second), 27 * time.Second, true},
+		{"retry in the text", errors.New("You exceeded your current quota. Please retry in 27.5s."), 27500 * time.Millisecond, true},
+		{"retryDelay in the text", errors.New(`{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "31s"}`), 31 * time.Second, true},
+		{"no hint", errors.New("internal error"), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := health.RetryAfter(tt.err)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestKeyAndQuotaErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		key   bool
+		quota bool
+	}{
+		{"unauthorized", statusError(http.StatusUnauthorized, "", ""), true, false},
+		{"forbidden", statusError(http.StatusForbidden, "", ""), true, false},
+		{"gemini key not valid", errors.New("googleapi: Error 400: API key not valid. Please pass a valid API key."), true, false},
+		{"anthropic key not valid", errors.New("invalid x-api-key"), true, false},
+		{"too many requests", statusError(http.StatusTooManyRequests, "", ""), true, true},
+		{"resource exhausted", grpcError(t, codes.ResourceExhausted, "", 0), true, true},
+		{"resource exhausted in the text", fmt.Errorf("generate: %w", errors.New("googleapi: Error 429: RESOURCE_EXHAUSTED")), true, true},
+		{"quota only mentioned", statusError(http.StatusBadRequest, "", "max_tokens exceeds the quota of this model"), false, false},
+		{"server error", statusError(http.StatusInternalServerError, "", ""), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.key, health.IsKeyError(tt.err))
+			assert.Equal(t, tt.quota, health.IsQuotaError(tt.err))
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	registry := health.NewRegistry(health.NewMemoryStore(), health.Config{ModelCooldown: time.Minute, KeyCooldown: time.Hour})
+
+	cooldown := registry.Report(ctx, "gemini", "gemini-2.0-flash", "key1", statusError(http.StatusTooManyRequests, "", ""))
+	assert.Equal(t, health.Fingerprint("key1"), cooldown.Key)
+	assert.Empty(t, cooldown.Model)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), cooldown.Until, time.Second)
+	assert.False(t, registry.KeyAvailable(ctx, "gemini", "key1"))
+	assert.True(t, registry.KeyAvailable(ctx, "gemini", "key2"))
+	assert.True(t, registry.ModelAvailable(ctx, "gemini", "gemini-2.0-flash"))
+
+	cooldown = registry.Report(ctx, "gemini", "gemini-1.5-pro", "key2", statusError(http.StatusServiceUnavailable, "5", ""))
+	assert.Equal(t, "gemini-1.5-pro", cooldown.Model)
+	assert.Empty(t, cooldown.Key)
+	assert.WithinDuration(t, time.Now().Add(5*time.Second), cooldown.Until, time.Second)
+	assert.False(t, registry.ModelAvailable(ctx, "gemini", "gemini-1.5-pro"))
+	assert.True(t, registry.KeyAvailable(ctx, "gemini", "key2"))
+
+	cooldowns, err := registry.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, cooldowns, 2)
+	assert.Equal(t, "gemini-1.5-pro", cooldowns[0].Model)
+}
+
+func TestReason(t *testing.T) {
+	registry := health.NewRegistry(health.NewMemoryStore(), health.Config{})
+	// 3 byte runes, the 200th byte is in the middle of one
+	cooldown := registry.Report(context.Background(), "gemini", "flash", "key1", errors.New(strings.Repeat("ก", 100)))
+	assert.True(t, utf8.ValidString(cooldown.Reason))
+	assert.Equal(t, strings.Repeat("ก", 66), cooldown.Reason)
+
+	cooldown = registry.Report(context.Background(), "gemini", "flash", "key1", errors.New("bad \xff byte"))
+	assert.Equal(t, "bad  byte", cooldown.Reason)
+}
@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryStore struct {
+	mu        sync.Mutex
+	cooldowns map[string]Cooldown
+}
+
+// NewMemoryStore keeps cooldowns in this process only.
+func NewMemoryStore() Store {
+	return &memoryStore{cooldowns: make(map[string]Cooldown)}
+}
+
+func (m *memoryStore) Put(ctx context.Context, id string, cooldown Cooldown) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cooldowns[id] = cooldown
+	return nil
+}
+
+func (m *memoryStore) Get(ctx context.Context, id string) (*Cooldown, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cooldown, ok := m.cooldowns[id]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(cooldown.Until) {
+		delete(m.cooldowns, id)
+		return nil, nil
+	}
+	return &cooldown, nil
+}
+
+func (m *memoryStore) List(ctx context.Context) ([]Cooldown, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var cooldowns []Cooldown
+	for id, cooldown := range m.cooldowns {
+		if time.Now().After(cooldown.Until) {
+			delete(m.cooldowns, id)
+			continue
+		}
+		cooldowns = append(cooldowns, cooldown)
+	}
+	return cooldowns, nil
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisPrefix = "ai:cooldown:"
+
+type redisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore shares cooldowns between every replica using the same Redis.
+// Entries expire on their own when the cooldown ends.
+func NewRedisStore(client *redis.Client) Store {
+	return &redisStore{client}
+}
+
+func (r *redisStore) Put(ctx context.Context, id string, cooldown Cooldown) error {
+	ttl := time.Until(cooldown.Until)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(cooldown)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, redisPrefix+id, data, ttl).Err()
+}
+
+func (r *redisStore) Get(ctx context.Context, id string) (*Cooldown, error) {
+	data, err := r.client.Get(ctx, redisPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cooldown Cooldown
+	if err := json.Unmarshal(data, &cooldown); err != nil {
+		return nil, err
+	}
+	return &cooldown, nil
+}
+
+func (r *redisStore) List(ctx context.Context) ([]Cooldown, error) {
+	var cooldowns []Cooldown
+	iter := r.client.Scan(ctx, 0, redisPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		cooldown, err := r.Get(ctx, iter.Val()[len(redisPrefix):])
+		if err != nil {
+			return nil, err
+		}
+		if cooldown != nil {
+			cooldowns = append(cooldowns, *cooldown)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return cooldowns, nil
+}
@@ -0,0 +1,213 @@
+package keypool
+
+import (
+	"ai/internal/health"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Strategy string
+
+const (
+	// Weighted favours keys that have been fast and reliable lately.
+	Weighted Strategy = "weighted"
+	// RoundRobin hands out the keys of a provider in turn.
+	RoundRobin Strategy = "round_robin"
+)
+
+// latencyDecay is the weight of the newest sample in the latency average.
+const latencyDecay = 0.2
+
+// Stats is the usage of one API key since the process started.
+type Stats struct {
+	Provider            string
+	Key                 string // fingerprint, see health.Fingerprint
+	Requests            int64
+	Failures            int64
+	QuotaErrors         int64
+	ConsecutiveFailures int64
+	InputTokens         int64
+	OutputTokens        int64
+	// Latency is a moving average of successful calls.
+	Latency time.Duration
+	// UsedToday counts requests since local midnight, to be checked against
+	// DailyQuota (0 means no quota).
+	UsedToday  int64
+	DailyQuota int64
+	Weight     float64
+}
+
+type keyState struct {
+	key string
+	Stats
+	day string
+}
+
+// Pool rotates the API keys of every provider and keeps per-key counters.
+// Counters live in this process; cooldowns of bad keys are kept by the
+// shared health registry.
+type Pool struct {
+	mu       sync.Mutex
+	strategy Strategy
+	keys     map[string][]*keyState
+	next     map[string]int
+	rand     *rand.Rand
+}
+
+func New(strategy Strategy) *Pool {
+	if strategy != RoundRobin {
+		strategy = Weighted
+	}
+	return &Pool{
+		strategy: strategy,
+		keys:     make(map[string][]*keyState),
+		next:     make(map[string]int),
+		rand:     rand.New(rand.NewPCG(uint64(time.Now().UnixNano()), 0)),
+	}
+}
+
+// Add registers the keys of provider ai. dailyQuota limits requests per key
+// per day, 0 for no limit.
+func (p *Pool) Add(ai string, keys []string, dailyQuota int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range keys {
+		p.keys[ai] = append(p.keys[ai], &keyState{
+			key:   k,
+			Stats: Stats{Provider: ai, Key: health.Fingerprint(k), DailyQuota: dailyQuota},
+		})
+	}
+}
+
+func today() string {
+	return time.Now().Format("2006-01-02")
+}
+
+// weight is 1 for a healthy key and shrinks with consecutive failures and
+// with latency, so slow or flaky keys still get some traffic to recover.
+func (s *keyState) weight() float64 {
+	w := 1.0 / float64(1+s.ConsecutiveFailures)
+	return w / (1 + s.Latency.Seconds()/10)
+}
+
+func (s *keyState) rollDay() {
+	if d := today(); s.day != d {
+		s.day = d
+		s.UsedToday = 0
+	}
+}
+
+// Pick returns a key of provider ai that usable accepts and that has quota
+// left today. ok is false when no such key exists. Picking does not count
+// as a request, Use does.
+func (p *Pool) Pick(ai string, usable func(key string) bool) (key string, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var candidates []*keyState
+	for _, s := range p.keys[ai] {
+		s.rollDay()
+		if s.DailyQuota > 0 && s.UsedToday >= s.DailyQuota {
+			continue
+		}
+		if usable != nil && !usable(s.key) {
+			continue
+		}
+		candidates = append(candidates, s)
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	var chosen *keyState
+	if p.strategy == RoundRobin {
+		chosen = candidates[p.next[ai]%len(candidates)]
+		p.next[ai]++
+	} else {
+		total := 0.0
+		for _, s := range candidates {
+			total += s.weight()
+		}
+		r := p.rand.Float64() * total
+		for _, s := range candidates {
+			chosen = s
+			if r -= s.weight(); r < 0 {
+				break
+			}
+		}
+	}
+	return chosen.key, true
+}
+
+// Use counts a call made with key against its daily quota.
+func (p *Pool) Use(ai string, key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.find(ai, key)
+	if s == nil {
+		return
+	}
+	s.rollDay()
+	s.Requests++
+	s.UsedToday++
+}
+
+func (p *Pool) find(ai string, key string) *keyState {
+	for _, s := range p.keys[ai] {
+		if s.key == key {
+			return s
+		}
+	}
+	return nil
+}
+
+// Success records a call that went through.
+func (p *Pool) Success(ai string, key string, latency time.Duration, inputTokens int, outputTokens int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.find(ai, key)
+	if s == nil {
+		return
+	}
+	s.ConsecutiveFailures = 0
+	s.InputTokens += int64(inputTokens)
+	s.OutputTokens += int64(outputTokens)
+	if s.Latency == 0 {
+		s.Latency = latency
+	} else {
+		s.Latency = time.Duration(latencyDecay*float64(latency) + (1-latencyDecay)*float64(s.Latency))
+	}
+}
+
+// Failure records a failed call; quota marks errors caused by an exhausted
+// quota.
+func (p *Pool) Failure(ai string, key string, quota bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.find(ai, key)
+	if s == nil {
+		return
+	}
+	s.Failures++
+	s.ConsecutiveFailures++
+	if quota {
+		s.QuotaErrors++
+	}
+}
+
+// Stats returns the counters of every key, grouped by provider.
+func (p *Pool) Stats() []Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var stats []Stats
+	for _, states := range p.keys {
+		for _, s := range states {
+			s.rollDay()
+			st := s.Stats
+			st.Weight = s.weight()
+			stats = append(stats, st)
+		}
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Provider < stats[j].Provider })
+	return stats
+}
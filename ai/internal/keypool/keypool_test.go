@@ -0,0 +1,86 @@
+package keypool_test
+
+import (
+	"ai/internal/health"
+	"ai/internal/keypool"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPick(t *testing.T) {
+	t.Run("round robin", func(t *testing.T) {
+		pool := keypool.New(keypool.RoundRobin)
+		pool.Add("gemini", []string{"a", "b", "c"}, 0)
+		var picked []string
+		for i := 0; i < 4; i++ {
+			key, ok := pool.Pick("gemini", nil)
+			assert.True(t, ok)
+			picked = append(picked, key)
+		}
+		assert.Equal(t, []string{"a", "b", "c", "a"}, picked)
+	})
+	t.Run("usable", func(t *testing.T) {
+		pool := keypool.New(keypool.RoundRobin)
+		pool.Add("gemini", []string{"a", "b"}, 0)
+		for i := 0; i < 3; i++ {
+			key, ok := pool.Pick("gemini", func(key string) bool { return key != "a" })
+			assert.True(t, ok)
+			assert.Equal(t, "b", key)
+		}
+		_, ok := pool.Pick("gemini", func(string) bool { return false })
+		assert.False(t, ok)
+		_, ok = pool.Pick("chatgpt", nil)
+		assert.False(t, ok)
+	})
+	t.Run("weighted", func(t *testing.T) {
+		pool := keypool.New(keypool.Weighted)
+		pool.Add("gemini", []string{"healthy", "flaky"}, 0)
+		for i := 0; i < 9; i++ {
+			pool.Failure("gemini", "flaky", false)
+		}
+		counts := map[string]int{}
+		for i := 0; i < 1000; i++ {
+			key, ok := pool.Pick("gemini", nil)
+			assert.True(t, ok)
+			counts[key]++
+		}
+		// weights are 1 and 1/10
+		assert.Greater(t, counts["healthy"], 800)
+		assert.Greater(t, counts["flaky"], 0)
+	})
+	t.Run("daily quota", func(t *testing.T) {
+		pool := keypool.New(keypool.RoundRobin)
+		pool.Add("gemini", []string{"a"}, 2)
+		for i := 0; i < 5; i++ {
+			// picking alone does not use up the quota
+			_, ok := pool.Pick("gemini", nil)
+			assert.True(t, ok)
+		}
+		pool.Use("gemini", "a")
+		pool.Use("gemini", "a")
+		_, ok := pool.Pick("gemini", nil)
+		assert.False(t, ok)
+	})
+}
+
+func TestStats(t *testing.T) {
+	pool := keypool.New(keypool.Weighted)
+	pool.Add("gemini", []string{"a"}, 100)
+	pool.Use("gemini", "a")
+	pool.Use("gemini", "a")
+	pool.Failure("gemini", "a", true)
+	pool.Success("gemini", "a", 0, 10, 20)
+	pool.Use("gemini", "unknown")
+
+	stats := pool.Stats()
+	assert.Len(t, stats, 1)
+	assert.Equal(t, health.Fingerprint("a"), stats[0].Key)
+	assert.Equal(t, int64(2), stats[0].Requests)
+	assert.Equal(t, int64(2), stats[0].UsedToday)
+	assert.Equal(t, int64(1), stats[0].Failures)
+	assert.Equal(t, int64(1), stats[0].QuotaErrors)
+	assert.Equal(t, int64(0), stats[0].ConsecutiveFailures)
+	assert.Equal(t, int64(30), stats[0].InputTokens+stats[0].OutputTokens)
+	assert.Equal(t, 1.0, stats[0].Weight)
+}
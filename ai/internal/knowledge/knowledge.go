@@ -0,0 +1,132 @@
+package knowledge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Languages an entry can be written in.
+const (
+	Thai    = "th"
+	English = "en"
+)
+
+// Languages lists the supported language codes in the order answers are
+// given.
+var Languages = []string{Thai, English}
+
+// ErrUnknownFormat is returned by Load for JSON that is neither a knowledge
+// base nor a legacy FAQ file.
+var ErrUnknownFormat = errors.New("not a knowledge base")
+
+// Base is a versioned knowledge base. Version is raised on every published
+// change and recorded with every answer given from it.
+type Base struct {
+	Version    int        `json:"version"`
+	Categories []Category `json:"categories"`
+}
+
+type Category struct {
+	ID      string  `json:"id"`
+	Name    Text    `json:"name"`
+	Entries []Entry `json:"entries"`
+}
+
+// Entry is one question with its curated answer.
+type Entry struct {
+	ID       string   `json:"id"`
+	Question Text     `json:"question"`
+	Answer   Text     `json:"answer"`
+	Tags     []string `json:"tags,omitempty"`
+	// EffectiveFrom and EffectiveUntil bound the days the entry is valid,
+	// both inclusive and optional.
+	EffectiveFrom  *Date `json:"effective_from,omitempty"`
+	EffectiveUntil *Date `json:"effective_until,omitempty"`
+	// Sources are the official pages the answer is taken from.
+	Sources []string `json:"sources,omitempty"`
+}
+
+// Text holds the variants of a text by language code.
+type Text map[string]string
+
+// Date is a calendar day written as 2006-01-02, in local time.
+type Date struct {
+	time.Time
+}
+
+const dateLayout = "2006-01-02"
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format(dateLayout))
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	t, err := time.ParseInLocation(dateLayout, s, time.Local)
+	if err != nil {
+		return fmt.Errorf("date %q must look like %s", s, dateLayout)
+	}
+	d.Time = t
+	return nil
+}
+
+// EffectiveAt reports whether the entry is valid at t.
+func (e *Entry) EffectiveAt(t time.Time) bool {
+	if e.EffectiveFrom != nil && t.Before(e.EffectiveFrom.Time) {
+		return false
+	}
+	if e.EffectiveUntil != nil && !t.Before(e.EffectiveUntil.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// Load parses a knowledge base and keeps what is valid of it: categories and
+// entries Validate rejects are dropped and returned as problems, see Prune.
+// Legacy FAQ files are converted with FromLegacy first. Any other JSON gives
+// ErrUnknownFormat.
+func Load(data []byte) (base *Base, problems []error, err error) {
+	base, err = decode(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if problems, err = Prune(base); err != nil {
+		return nil, nil, err
+	}
+	return base, problems, nil
+}
+
+// Parse reads a knowledge base strictly: unknown fields are errors, and so
+// is anything Validate rejects.
+func Parse(data []byte) (*Base, error) {
+	base, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(base); err != nil {
+		return nil, err
+	}
+	return base, nil
+}
+
+// decode reads a knowledge base, or a legacy FAQ file, without validating
+// it. Unknown fields of a knowledge base are errors.
+func decode(data []byte) (*Base, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil || probe["categories"] == nil {
+		return FromLegacy(data)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var base Base
+	if err := decoder.Decode(&base); err != nil {
+		return nil, err
+	}
+	return &base, nil
+}
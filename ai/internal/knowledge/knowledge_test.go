@@ -0,0 +1,112 @@
+package knowledge_test
+
+import (
+	"ai/internal/knowledge"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const base = `{
+	"version": 3,
+	"categories": [{
+		"id": "graduation",
+		"name": {"th": "การขอสำเร็จการศึกษา", "en": "Graduation"},
+		"entries": [{
+			"id": "graduation-1",
+			"question": {"th": "การขอสำเร็จการศึกษา จะต้องดำเนินการอย่างไรบ้าง", "en": "How do I apply for graduation?"},
+			"answer": {"th": "ยื่นเอกสาร", "en": "Submit the documents"},
+			"tags": ["graduation"],
+			"effective_from": "2025-01-01",
+			"effective_until": "2025-12-31",
+			"sources": ["https://gs.kku.ac.th/"]
+		}]
+	}]
+}`
+
+func TestLoad(t *testing.T) {
+	t.Run("typed", func(t *testing.T) {
+		b, _, err := knowledge.Load([]byte(base))
+		assert.NoError(t, err)
+		assert.Equal(t, 3, b.Version)
+		e := b.Categories[0].Entries[0]
+		assert.Equal(t, "Submit the documents", e.Answer[knowledge.English])
+		assert.False(t, e.EffectiveAt(time.Date(2024, 12, 31, 23, 0, 0, 0, time.Local)))
+		assert.True(t, e.EffectiveAt(time.Date(2025, 12, 31, 23, 0, 0, 0, time.Local)))
+		assert.False(t, e.EffectiveAt(time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)))
+	})
+	t.Run("legacy", func(t *testing.T) {
+		b, _, err := knowledge.Load([]byte(`{"data": [
+			{"_comment": "ลงทะเบียน", "_order": 1, "question": "ลงทะเบียนอย่างไร", "answer": ["ผ่านระบบ", "ชำระเงิน"]},
+			{"_order": 2, "question": "ลาพักอย่างไร", "answer": "ยื่นคำร้อง"},
+			{"_comment": "สำเร็จการศึกษา", "_order": 1, "question": "ขอจบอย่างไร", "answer": "ยื่นเอกสาร"}
+		]}`))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, b.Version)
+		assert.Len(t, b.Categories, 2)
+		assert.Equal(t, "category-1-2", b.Categories[0].Entries[1].ID)
+		assert.Equal(t, "ผ่านระบบ\nชำระเงิน", b.Categories[0].Entries[0].Answer[knowledge.Thai])
+	})
+	t.Run("unknown format", func(t *testing.T) {
+		_, _, err := knowledge.Load([]byte(`[{"building": "EN16101"}]`))
+		assert.ErrorIs(t, err, knowledge.ErrUnknownFormat)
+	})
+	t.Run("unknown field", func(t *testing.T) {
+		_, _, err := knowledge.Load([]byte(`{"version": 1, "categories": [], "owner": "x"}`))
+		assert.ErrorContains(t, err, "owner")
+	})
+	t.Run("invalid entries", func(t *testing.T) {
+		b, problems, err := knowledge.Load([]byte(`{
+			"version": 2,
+			"categories": [
+				{"id": "Fees", "name": {"th": "ค่าธรรมเนียม"}, "entries": [{"id": "fees-1", "question": {"th": "ค่าเทอม"}, "answer": {"th": "ตามประกาศ"}}]},
+				{"id": "leave", "name": {"th": "ลาพัก"}, "entries": [
+					{"id": "leave-1", "question": {"th": "ลาพักอย่างไร"}, "answer": {"th": "ยื่นคำร้อง"}},
+					{"id": "leave-2", "question": {"th": "ลาออกอย่างไร"}, "answer": {"en": "Submit"}}
+				]}
+			]
+		}`))
+		assert.NoError(t, err)
+		// the bad category and entry are dropped, the rest is kept
+		assert.Len(t, b.Categories, 1)
+		assert.Len(t, b.Categories[0].Entries, 1)
+		assert.Equal(t, "leave-1", b.Categories[0].Entries[0].ID)
+		if assert.Len(t, problems, 2) {
+			assert.ErrorContains(t, problems[0], `categories[0]: id "Fees" must be lower case`)
+			assert.ErrorContains(t, problems[1], "categories[1].entries[1]")
+		}
+	})
+	t.Run("nothing valid", func(t *testing.T) {
+		_, _, err := knowledge.Load([]byte(`{"version": 1, "categories": [{"id": "leave", "name": {"th": "ลาพัก"}, "entries": [{"id": "leave-1", "question": {"th": "ลาพัก"}}]}]}`))
+		assert.ErrorContains(t, err, "no valid entries")
+	})
+}
+
+func TestValidate(t *testing.T) {
+	_, err := knowledge.Parse([]byte(`{
+		"version": 0,
+		"categories": [{
+			"id": "Graduation",
+			"name": {"th": "สำเร็จการศึกษา"},
+			"entries": [
+				{"id": "a", "question": {"th": "ขอจบ", "fr": "diplôme"}, "answer": {"th": "ยื่นเอกสาร"}, "sources": ["gs.kku.ac.th"]},
+				{"id": "a", "question": {"th": "ขอจบ"}, "answer": {"en": "Submit"}, "effective_from": "2025-02-01", "effective_until": "2025-01-01"}
+			]
+		}]
+	}`))
+	if assert.Error(t, err) {
+		for _, want := range []string{
+			"version must be 1 or more",
+			`id "Graduation" must be lower case`,
+			`unsupported language "fr"`,
+			`question in "fr" has no answer`,
+			`source "gs.kku.ac.th" is not an http(s) URL`,
+			`duplicate id "a"`,
+			`question in "th" has no answer in "th"`,
+			"effective_until is before effective_from",
+		} {
+			assert.ErrorContains(t, err, want)
+		}
+	}
+}
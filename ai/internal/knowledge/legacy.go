@@ -0,0 +1,85 @@
+package knowledge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// legacyEntry is an entry of the original question.json: a "_comment" starts
+// a new category, "_order" numbers the entries by hand and "answer" is a
+// string or a list of lines. Everything is in Thai.
+type legacyEntry struct {
+	Comment  string          `json:"_comment"`
+	Order    int             `json:"_order"`
+	Question string          `json:"question"`
+	Answer   json.RawMessage `json:"answer"`
+}
+
+// FromLegacy converts a legacy FAQ file, {"data": [...]} or a bare array, to
+// version 1 of a knowledge base. Categories are named after the comments and
+// numbered, entries are numbered within their category. The result is not
+// validated.
+func FromLegacy(data []byte) (*Base, error) {
+	var raw []json.RawMessage
+	var wrapped struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Data != nil {
+		raw = wrapped.Data
+	} else if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, ErrUnknownFormat
+	}
+	entries := make([]legacyEntry, len(raw))
+	questions := 0
+	for i, r := range raw {
+		if err := json.Unmarshal(r, &entries[i]); err != nil {
+			return nil, ErrUnknownFormat
+		}
+		if entries[i].Question != "" {
+			questions++
+		}
+	}
+	if questions == 0 {
+		// not a FAQ, e.g. some other JSON uploaded as knowledge
+		return nil, ErrUnknownFormat
+	}
+	base := &Base{Version: 1}
+	for _, e := range entries {
+		if e.Comment != "" || len(base.Categories) == 0 {
+			n := len(base.Categories) + 1
+			name := e.Comment
+			if name == "" {
+				name = "ทั่วไป" // general
+			}
+			base.Categories = append(base.Categories, Category{
+				ID:   fmt.Sprintf("category-%d", n),
+				Name: Text{Thai: name},
+			})
+		}
+		c := &base.Categories[len(base.Categories)-1]
+		// a missing question or answer is left empty for Validate to report
+		answer, err := legacyAnswer(e.Answer)
+		if err != nil && e.Answer != nil {
+			return nil, fmt.Errorf("%q: %w", e.Question, err)
+		}
+		c.Entries = append(c.Entries, Entry{
+			ID:       fmt.Sprintf("%s-%d", c.ID, len(c.Entries)+1),
+			Question: Text{Thai: e.Question},
+			Answer:   Text{Thai: answer},
+		})
+	}
+	return base, nil
+}
+
+func legacyAnswer(raw json.RawMessage) (string, error) {
+	var one string
+	if err := json.Unmarshal(raw, &one); err == nil {
+		return one, nil
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return "", fmt.Errorf("answer must be a string or a list of strings")
+	}
+	return strings.Join(many, "\n"), nil
+}
@@ -0,0 +1,141 @@
+package knowledge
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Validate reports every problem of base at once, so a broken file can be
+// fixed in one go.
+func Validate(base *Base) error {
+	errs := validateBase(base)
+	categories := map[string]bool{}
+	entries := map[string]bool{}
+	for i, c := range base.Categories {
+		at := fmt.Sprintf("categories[%d]", i)
+		errs = append(errs, validateCategory(at, &c, categories)...)
+		for j, e := range c.Entries {
+			errs = append(errs, validateEntry(fmt.Sprintf("%s.entries[%d]", at, j), &e, entries)...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Prune drops the categories and entries of base that Validate rejects and
+// returns their problems, so one bad entry does not take the rest of the
+// knowledge base down. err is set instead when nothing of base is usable: its
+// version is wrong or no valid entry is left.
+func Prune(base *Base) (problems []error, err error) {
+	if errs := validateBase(base); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	categories := map[string]bool{}
+	entries := map[string]bool{}
+	var kept []Category
+	for i, c := range base.Categories {
+		at := fmt.Sprintf("categories[%d]", i)
+		if errs := validateCategory(at, &c, categories); len(errs) > 0 {
+			problems = append(problems, errs...)
+			continue
+		}
+		var valid []Entry
+		for j, e := range c.Entries {
+			if errs := validateEntry(fmt.Sprintf("%s.entries[%d]", at, j), &e, entries); len(errs) > 0 {
+				problems = append(problems, errs...)
+				continue
+			}
+			valid = append(valid, e)
+		}
+		if len(valid) == 0 {
+			problems = append(problems, fmt.Errorf("%s: no valid entries", at))
+			continue
+		}
+		c.Entries = valid
+		kept = append(kept, c)
+	}
+	base.Categories = kept
+	if len(kept) == 0 {
+		return nil, errors.Join(append([]error{errors.New("no valid entries")}, problems...)...)
+	}
+	return problems, nil
+}
+
+func validateBase(base *Base) []error {
+	var errs []error
+	if base.Version < 1 {
+		errs = append(errs, fmt.Errorf("version must be 1 or more"))
+	}
+	if len(base.Categories) == 0 {
+		errs = append(errs, fmt.Errorf("no categories"))
+	}
+	return errs
+}
+
+func validateCategory(at string, c *Category, seen map[string]bool) []error {
+	var errs []error
+	if !idPattern.MatchString(c.ID) {
+		errs = append(errs, fmt.Errorf("%s: id %q must be lower case letters, digits and dashes", at, c.ID))
+	} else if seen[c.ID] {
+		errs = append(errs, fmt.Errorf("%s: duplicate id %q", at, c.ID))
+	}
+	seen[c.ID] = true
+	errs = append(errs, validateText(at+".name", c.Name)...)
+	if len(c.Entries) == 0 {
+		errs = append(errs, fmt.Errorf("%s: no entries", at))
+	}
+	return errs
+}
+
+func validateEntry(at string, e *Entry, seen map[string]bool) []error {
+	var errs []error
+	if !idPattern.MatchString(e.ID) {
+		errs = append(errs, fmt.Errorf("%s: id %q must be lower case letters, digits and dashes", at, e.ID))
+	} else if seen[e.ID] {
+		errs = append(errs, fmt.Errorf("%s: duplicate id %q", at, e.ID))
+	}
+	seen[e.ID] = true
+	errs = append(errs, validateText(at+".question", e.Question)...)
+	errs = append(errs, validateText(at+".answer", e.Answer)...)
+	for lang := range e.Question {
+		if strings.TrimSpace(e.Answer[lang]) == "" {
+			errs = append(errs, fmt.Errorf("%s: question in %q has no answer in %q", at, lang, lang))
+		}
+	}
+	for _, tag := range e.Tags {
+		if strings.TrimSpace(tag) == "" || tag != strings.ToLower(tag) {
+			errs = append(errs, fmt.Errorf("%s: tag %q must be lower case and not empty", at, tag))
+		}
+	}
+	for _, source := range e.Sources {
+		u, err := url.Parse(source)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s: source %q is not an http(s) URL", at, source))
+		}
+	}
+	if e.EffectiveFrom != nil && e.EffectiveUntil != nil && e.EffectiveUntil.Before(e.EffectiveFrom.Time) {
+		errs = append(errs, fmt.Errorf("%s: effective_until is before effective_from", at))
+	}
+	return errs
+}
+
+func validateText(at string, text Text) []error {
+	var errs []error
+	if len(text) == 0 {
+		errs = append(errs, fmt.Errorf("%s: empty", at))
+	}
+	for lang, s := range text {
+		if !slices.Contains(Languages, lang) {
+			errs = append(errs, fmt.Errorf("%s: unsupported language %q", at, lang))
+		}
+		if strings.TrimSpace(s) == "" {
+			errs = append(errs, fmt.Errorf("%s: empty %q text", at, lang))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,160 @@
+// Package prompt renders the prompts sent to the models from text/template
+// files, so their wording can change without a rebuild.
+//
+// The templates of a version live in <dir>/<version>/. <name>.tmpl is the
+// template of a prompt and <name>.<provider>.tmpl, e.g. system.claude.tmpl,
+// its variant for one provider. Files starting with an underscore hold
+// definitions every template of the version can use.
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// Names of the prompts.
+const (
+	// System is the system prompt of every question.
+	System = "system"
+	// Grounded asks Gemini with Google Search when the model did not know.
+	Grounded = "grounded"
+	// Handoff tells that staff will answer the question.
+	Handoff = "handoff"
+	// Unknown tells that the information is unavailable.
+	Unknown = "unknown"
+	// Refused tells that the question cannot be answered, when the provider
+	// blocked it.
+	Refused = "refused"
+)
+
+// Data are the variables of a template.
+type Data struct {
+	// Date is today in Config.Location, e.g. 2025-01-31. Render fills it in.
+	Date string
+	// Lang is th or en to answer in that language only, empty for both.
+	Lang string
+	// JSON is set when the answer is asked for as a JSON object.
+	JSON bool
+	// Knowledge is the context retrieved from the knowledge base.
+	Knowledge string
+	Question  string
+}
+
+type Config struct {
+	// Dir defaults to ./prompts.
+	Dir string
+	// Version defaults to the last version in Dir in sort order.
+	Version string
+	// Location defaults to Thailand time.
+	Location *time.Location
+}
+
+// Set holds the templates of one version. A reload that fails keeps the
+// templates in use.
+type Set struct {
+	config  Config
+	current atomic.Pointer[snapshot]
+}
+
+type snapshot struct {
+	version string
+	// templates are keyed by file name without .tmpl, e.g. system.claude.
+	templates map[string]*template.Template
+}
+
+func NewSet(config Config) *Set {
+	if config.Dir == "" {
+		config.Dir = "./prompts"
+	}
+	if config.Location == nil {
+		config.Location = time.FixedZone("ICT", 7*60*60)
+	}
+	return &Set{config: config}
+}
+
+// Reload parses the templates of the configured version.
+func (s *Set) Reload() error {
+	version := s.config.Version
+	if version == "" {
+		var err error
+		if version, err = latest(s.config.Dir); err != nil {
+			return err
+		}
+	}
+	dir := filepath.Join(s.config.Dir, version)
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return err
+	}
+	var shared, prompts []string
+	for _, f := range files {
+		if strings.HasPrefix(filepath.Base(f), "_") {
+			shared = append(shared, f)
+		} else {
+			prompts = append(prompts, f)
+		}
+	}
+	next := &snapshot{version: version, templates: map[string]*template.Template{}}
+	for _, f := range prompts {
+		t, err := template.New(filepath.Base(f)).Option("missingkey=error").ParseFiles(append([]string{f}, shared...)...)
+		if err != nil {
+			return err
+		}
+		next.templates[strings.TrimSuffix(filepath.Base(f), ".tmpl")] = t
+	}
+	if next.templates[System] == nil {
+		return fmt.Errorf("prompt version %s has no %s.tmpl", version, System)
+	}
+	s.current.Store(next)
+	return nil
+}
+
+// latest is the last directory in dir in sort order.
+func latest(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var versions []string
+	for _, e := range entries {
+		if e.IsDir() {
+			versions = append(versions, e.Name())
+		}
+	}
+	if len(versions) == 0 {
+		return "", fmt.Errorf("no prompt versions in %s", dir)
+	}
+	sort.Strings(versions)
+	return versions[len(versions)-1], nil
+}
+
+// Render executes the prompt name for provider, its variant when it has one.
+// version tells which templates were used, e.g. v1 or v1/claude.
+func (s *Set) Render(name string, provider string, data Data) (text string, version string, err error) {
+	current := s.current.Load()
+	if current == nil {
+		return "", "", fmt.Errorf("prompts are not loaded")
+	}
+	version = current.version
+	t, ok := current.templates[name+"."+provider]
+	if ok {
+		version += "/" + provider
+	} else if t, ok = current.templates[name]; !ok {
+		return "", "", fmt.Errorf("prompt version %s has no %s.tmpl", current.version, name)
+	}
+	if data.Date == "" {
+		data.Date = time.Now().In(s.config.Location).Format(time.DateOnly)
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", "", err
+	}
+	return b.String(), version, nil
+}
@@ -0,0 +1,114 @@
+package prompt_test
+
+import (
+	"ai/internal/prompt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTemplates(t *testing.T, dir string, files map[string]string) {
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(text), 0644))
+	}
+}
+
+func TestShipped(t *testing.T) {
+	s := prompt.NewSet(prompt.Config{Dir: "../../prompts"})
+	assert.NoError(t, s.Reload())
+
+	t.Run("system", func(t *testing.T) {
+		text, version, err := s.Render(prompt.System, "gemini", prompt.Data{Knowledge: "[question.json#thesis-exam-2]\nQ: ...", Date: "2025-01-31"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, version)
+		assert.Contains(t, text, "[question.json#thesis-exam-2]")
+		assert.Contains(t, text, "Today is 2025-01-31.")
+		assert.Contains(t, text, "both Thai and English")
+		assert.Contains(t, text, "ภาษาไทย: อยู่ข้างตึก 50\nEnglish: Near 50th anniversary building")
+	})
+	t.Run("system in Thai as JSON", func(t *testing.T) {
+		text, _, err := s.Render(prompt.System, "chatgpt", prompt.Data{Lang: "th", JSON: true})
+		assert.NoError(t, err)
+		assert.Contains(t, text, "Thai only")
+		assert.Contains(t, text, `{"outcome": "answered", "th": "อยู่ข้างตึก 50"}`)
+		assert.Contains(t, text, `{"outcome": "unknown"}`)
+		assert.Contains(t, text, "JSON object")
+	})
+	t.Run("system as text", func(t *testing.T) {
+		text, _, err := s.Render(prompt.System, "gemini", prompt.Data{Lang: "en"})
+		assert.NoError(t, err)
+		assert.Contains(t, text, "[UNKNOWN]")
+		assert.Contains(t, text, "[REFUSED]")
+		assert.NotContains(t, text, "outcome")
+	})
+	t.Run("notices", func(t *testing.T) {
+		for _, name := range []string{prompt.Handoff, prompt.Unknown, prompt.Refused} {
+			text, _, err := s.Render(name, "", prompt.Data{})
+			assert.NoError(t, err)
+			assert.Contains(t, text, "ภาษาไทย: ")
+			assert.Contains(t, text, "\nEnglish: ")
+			text, _, err = s.Render(name, "", prompt.Data{Lang: "en"})
+			assert.NoError(t, err)
+			assert.NotContains(t, text, "ภาษาไทย")
+		}
+	})
+	t.Run("grounded", func(t *testing.T) {
+		text, _, err := s.Render(prompt.Grounded, "gemini", prompt.Data{Question: "Where is EN16101?"})
+		assert.NoError(t, err)
+		assert.Contains(t, text, "User Query:\nWhere is EN16101?")
+	})
+}
+
+func TestSet(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplates(t, dir, map[string]string{
+		"v1/system.tmpl":        "old",
+		"v2/_shared.tmpl":       `{{define "lang"}}{{if .Lang}}{{.Lang}}{{else}}th+en{{end}}{{end}}`,
+		"v2/system.tmpl":        `answer in {{template "lang" .}}`,
+		"v2/system.claude.tmpl": `claude answers in {{template "lang" .}}`,
+	})
+
+	t.Run("latest version", func(t *testing.T) {
+		s := prompt.NewSet(prompt.Config{Dir: dir})
+		assert.NoError(t, s.Reload())
+		text, version, err := s.Render(prompt.System, "gemini", prompt.Data{})
+		assert.NoError(t, err)
+		assert.Equal(t, "answer in th+en", text)
+		assert.Equal(t, "v2", version)
+	})
+	t.Run("provider variant", func(t *testing.T) {
+		s := prompt.NewSet(prompt.Config{Dir: dir})
+		assert.NoError(t, s.Reload())
+		text, version, err := s.Render(prompt.System, "claude", prompt.Data{Lang: "en"})
+		assert.NoError(t, err)
+		assert.Equal(t, "claude answers in en", text)
+		assert.Equal(t, "v2/claude", version)
+	})
+	t.Run("pinned version", func(t *testing.T) {
+		s := prompt.NewSet(prompt.Config{Dir: dir, Version: "v1"})
+		assert.NoError(t, s.Reload())
+		text, version, err := s.Render(prompt.System, "claude", prompt.Data{})
+		assert.NoError(t, err)
+		assert.Equal(t, "old", text)
+		assert.Equal(t, "v1", version)
+	})
+	t.Run("broken template keeps the previous", func(t *testing.T) {
+		s := prompt.NewSet(prompt.Config{Dir: dir, Version: "v1"})
+		assert.NoError(t, s.Reload())
+		writeTemplates(t, dir, map[string]string{"v1/system.tmpl": "{{if}"})
+		assert.Error(t, s.Reload())
+		text, _, err := s.Render(prompt.System, "gemini", prompt.Data{})
+		assert.NoError(t, err)
+		assert.Equal(t, "old", text)
+	})
+	t.Run("missing prompt", func(t *testing.T) {
+		s := prompt.NewSet(prompt.Config{Dir: dir, Version: "v2"})
+		assert.NoError(t, s.Reload())
+		_, _, err := s.Render(prompt.Grounded, "gemini", prompt.Data{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,69 @@
+package prompt
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// settle is how long Watch waits after the last change before reloading.
+const settle = 300 * time.Millisecond
+
+// Watch reloads the templates whenever a template file changes or, without
+// a configured version, a version is added, until ctx is done.
+func (s *Set) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(s.config.Dir); err != nil {
+		watcher.Close()
+		return err
+	}
+	s.watchVersion(watcher)
+	go func() {
+		defer watcher.Close()
+		timer := time.NewTimer(settle)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op != fsnotify.Chmod {
+					timer.Reset(settle)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Prompt watcher: %v", err)
+			case <-timer.C:
+				if err := s.Reload(); err != nil {
+					log.Printf("Keeping the previous prompts: %v", err)
+					continue
+				}
+				s.watchVersion(watcher)
+			}
+		}
+	}()
+	return nil
+}
+
+// watchVersion adds the directory of the version in use to watcher.
+func (s *Set) watchVersion(watcher *fsnotify.Watcher) {
+	current := s.current.Load()
+	if current == nil {
+		return
+	}
+	if err := watcher.Add(filepath.Join(s.config.Dir, current.version)); err != nil {
+		log.Printf("Prompt watcher: %v", err)
+	}
+}
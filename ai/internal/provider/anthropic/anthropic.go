@@ -0,0 +1,285 @@
+package anthropic
+
+import (
+	"ai/internal/provider"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const apiVersion = "2023-06-01"
+
+// jsonPrefill starts the answer of a JSON request. The Messages API has no
+// JSON mode; a reply that already begins with "{" keeps the model to the
+// object the prompt describes. It is not part of the returned text, so it
+// is added back.
+const jsonPrefill = "{"
+
+type Config struct {
+	// BaseURL defaults to https://api.anthropic.com.
+	BaseURL string
+	// MaxTokens is required by the Messages API, defaults to 1024.
+	MaxTokens  int
+	HTTPClient *http.Client
+}
+
+type anthropic struct {
+	config Config
+}
+
+func New(config Config) provider.Provider {
+	if config.BaseURL == "" {
+		config.BaseURL = "https://api.anthropic.com"
+	}
+	if config.MaxTokens == 0 {
+		config.MaxTokens = 1024
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	return &anthropic{config}
+}
+
+func (a *anthropic) Name() string {
+	return "claude"
+}
+
+func (a *anthropic) Modalities() []provider.Modality {
+	return []provider.Modality{provider.Text, provider.Image}
+}
+
+type messagesRequest struct {
+	Model     string    `json:"model"`
+	MaxTokens int       `json:"max_tokens"`
+	System    string    `json:"system,omitempty"`
+	Messages  []message `json:"messages"`
+	Stream    bool      `json:"stream,omitempty"`
+}
+
+type message struct {
+	Role    string         `json:"role"`
+	Content []contentBlock `json:"content"`
+}
+
+type contentBlock struct {
+	Type   string       `json:"type"`
+	Text   string       `json:"text,omitempty"`
+	Source *imageSource `json:"source,omitempty"`
+}
+
+type imageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type messagesResponse struct {
+	Model      string         `json:"model"`
+	Content    []contentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      usage          `json:"usage"`
+}
+
+type usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// streamEvent covers the fields used from every server-sent event type.
+type streamEvent struct {
+	Type    string           `json:"type"`
+	Message messagesResponse `json:"message"`
+	Delta   struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage usage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (a *anthropic) Generate(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	resp, err := a.do(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var msg messagesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	var text strings.Builder
+	if req.JSON {
+		text.WriteString(jsonPrefill)
+	}
+	for _, block := range msg.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	res := &provider.Response{
+		Text:         text.String(),
+		Model:        msg.Model,
+		FinishReason: msg.StopReason,
+		InputTokens:  msg.Usage.InputTokens,
+		OutputTokens: msg.Usage.OutputTokens,
+	}
+	if res.Model == "" {
+		res.Model = req.Model
+	}
+	if err := blocked(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (a *anthropic) Stream(ctx context.Context, req *provider.Request, send provider.StreamFunc) (*provider.Response, error) {
+	resp, err := a.do(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res := &provider.Response{Model: req.Model}
+	var answer strings.Builder
+	prefix := ""
+	if req.JSON {
+		prefix = jsonPrefill
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var event streamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stream event: %w", err)
+		}
+		switch event.Type {
+		case "message_start":
+			if event.Message.Model != "" {
+				res.Model = event.Message.Model
+			}
+			res.InputTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			if event.Delta.Type != "text_delta" || event.Delta.Text == "" {
+				continue
+			}
+			text := prefix + event.Delta.Text
+			prefix = ""
+			answer.WriteString(text)
+			if err := send(text); err != nil {
+				return nil, err
+			}
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				res.FinishReason = event.Delta.StopReason
+			}
+			res.OutputTokens = event.Usage.OutputTokens
+		case "error":
+			return nil, fmt.Errorf("claude: %s: %s", event.Error.Type, event.Error.Message)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	res.Text = answer.String()
+	if err := blocked(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// blocked returns provider.ErrBlocked for an answer the model refused to
+// give for safety.
+func blocked(res *provider.Response) error {
+	if res.FinishReason == "refusal" {
+		return fmt.Errorf("%w: stop reason %s", provider.ErrBlocked, res.FinishReason)
+	}
+	return nil
+}
+
+func (a *anthropic) do(ctx context.Context, req *provider.Request, stream bool) (*http.Response, error) {
+	jsonData, err := json.Marshal(messagesRequest{
+		Model:     req.Model,
+		MaxTokens: a.config.MaxTokens,
+		System:    req.System,
+		Messages:  messages(req),
+		Stream:    stream,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON payload: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.BaseURL+"/v1/messages", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", req.APIKey)
+	httpReq.Header.Set("anthropic-version", apiVersion)
+	resp, err := a.config.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, provider.NewStatusError(a.Name(), resp, bodyBytes)
+	}
+	return resp, nil
+}
+
+// messages builds the conversation. The Messages API wants turns to
+// alternate and start with the user, so leading assistant turns are dropped
+// and consecutive turns of the same role are merged.
+func messages(req *provider.Request) []message {
+	var msgs []message
+	add := func(role string, blocks ...contentBlock) {
+		if len(msgs) == 0 && role != "user" {
+			return
+		}
+		if n := len(msgs); n > 0 && msgs[n-1].Role == role {
+			msgs[n-1].Content = append(msgs[n-1].Content, blocks...)
+			return
+		}
+		msgs = append(msgs, message{Role: role, Content: blocks})
+	}
+	for _, m := range req.History {
+		role := "user"
+		if m.Role == "assistant" {
+			role = "assistant"
+		}
+		add(role, contentBlock{Type: "text", Text: m.Content})
+	}
+	var blocks []contentBlock
+	if len(req.Image) > 0 {
+		mediaType := req.ImageMimeType
+		if mediaType == "" {
+			mediaType = "image/jpeg"
+		}
+		blocks = append(blocks, contentBlock{Type: "image", Source: &imageSource{
+			Type:      "base64",
+			MediaType: mediaType,
+			Data:      base64.StdEncoding.EncodeToString(req.Image),
+		}})
+	}
+	blocks = append(blocks, contentBlock{Type: "text", Text: req.Prompt})
+	add("user", blocks...)
+	if req.JSON {
+		add("assistant", contentBlock{Type: "text", Text: jsonPrefill})
+	}
+	return msgs
+}
@@ -0,0 +1,184 @@
+package anthropic_test
+
+import (
+	"ai/internal/provider"
+	"ai/internal/provider/anthropic"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "sk-ant-test", r.Header.Get("x-api-key"))
+		assert.Equal(t, "2023-06-01", r.Header.Get("anthropic-version"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		fmt.Fprint(w, `{"model":"claude-3-5-haiku-20241022","content":[{"type":"text","text":"\"answer\":\"สวัสดี\"}"}],"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":5}}`)
+	}))
+	defer server.Close()
+
+	p := anthropic.New(anthropic.Config{BaseURL: server.URL + "/", MaxTokens: 256})
+
+	t.Run("success", func(t *testing.T) {
+		resp, err := p.Generate(context.Background(), &provider.Request{
+			Model:  "claude-3-5-haiku-latest",
+			APIKey: "sk-ant-test",
+			System: "be brief",
+			Prompt: "hello",
+			History: []provider.Message{
+				{Role: "assistant", Content: "welcome"},
+				{Role: "user", Content: "hi"},
+				{Role: "user", Content: "are you there?"},
+				{Role: "assistant", Content: "hey"},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, `"answer":"สวัสดี"}`, resp.Text)
+		assert.Equal(t, "claude-3-5-haiku-20241022", resp.Model)
+		assert.Equal(t, "end_turn", resp.FinishReason)
+		assert.Equal(t, 12, resp.InputTokens)
+		assert.Equal(t, 5, resp.OutputTokens)
+
+		assert.Equal(t, "claude-3-5-haiku-latest", got["model"])
+		assert.Equal(t, float64(256), got["max_tokens"])
+		assert.Equal(t, "be brief", got["system"])
+		assert.Nil(t, got["stream"])
+		// the leading assistant turn is dropped and the two user turns merged
+		msgs := got["messages"].([]interface{})
+		assert.Len(t, msgs, 3)
+		first := msgs[0].(map[string]interface{})
+		assert.Equal(t, "user", first["role"])
+		assert.Len(t, first["content"], 2)
+		assert.Equal(t, "assistant", msgs[1].(map[string]interface{})["role"])
+		last := msgs[2].(map[string]interface{})
+		assert.Equal(t, "user", last["role"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"type": "text", "text": "hello"}}, last["content"])
+	})
+	t.Run("json", func(t *testing.T) {
+		resp, err := p.Generate(context.Background(), &provider.Request{Model: "claude-3-5-haiku-latest", APIKey: "sk-ant-test", Prompt: "hello", JSON: true})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"answer":"สวัสดี"}`, resp.Text)
+		msgs := got["messages"].([]interface{})
+		assert.Len(t, msgs, 2)
+		assert.Equal(t, map[string]interface{}{
+			"role":    "assistant",
+			"content": []interface{}{map[string]interface{}{"type": "text", "text": "{"}},
+		}, msgs[1])
+	})
+	t.Run("image", func(t *testing.T) {
+		_, err := p.Generate(context.Background(), &provider.Request{
+			Model:         "claude-3-5-haiku-latest",
+			APIKey:        "sk-ant-test",
+			Prompt:        "what does this notice say?",
+			Image:         []byte("png"),
+			ImageMimeType: "image/png",
+		})
+		assert.NoError(t, err)
+		msgs := got["messages"].([]interface{})
+		assert.Len(t, msgs, 1)
+		content := msgs[0].(map[string]interface{})["content"].([]interface{})
+		assert.Len(t, content, 2)
+		assert.Equal(t, map[string]interface{}{
+			"type":   "image",
+			"source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": base64.StdEncoding.EncodeToString([]byte("png"))},
+		}, content[0])
+		assert.Equal(t, "what does this notice say?", content[1].(map[string]interface{})["text"])
+	})
+}
+
+func TestStream(t *testing.T) {
+	var jsonMode bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, true, body["stream"])
+		msgs := body["messages"].([]interface{})
+		jsonMode = msgs[len(msgs)-1].(map[string]interface{})["role"] == "assistant"
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-3-5-haiku-20241022\",\"usage\":{\"input_tokens\":9}}}\n\n")
+		fmt.Fprint(w, "event: ping\ndata: {\"type\":\"ping\"}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hel\"}}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"lo\"}}\n\n")
+		fmt.Fprint(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":2}}\n\n")
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+	defer server.Close()
+
+	p := anthropic.New(anthropic.Config{BaseURL: server.URL})
+
+	t.Run("success", func(t *testing.T) {
+		var chunks []string
+		resp, err := p.Stream(context.Background(), &provider.Request{Model: "claude-3-5-haiku-latest", Prompt: "hi"}, func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.False(t, jsonMode)
+		assert.Equal(t, []string{"Hel", "lo"}, chunks)
+		assert.Equal(t, "Hello", resp.Text)
+		assert.Equal(t, "claude-3-5-haiku-20241022", resp.Model)
+		assert.Equal(t, "end_turn", resp.FinishReason)
+		assert.Equal(t, 9, resp.InputTokens)
+		assert.Equal(t, 2, resp.OutputTokens)
+	})
+	t.Run("json", func(t *testing.T) {
+		var chunks []string
+		resp, err := p.Stream(context.Background(), &provider.Request{Model: "claude-3-5-haiku-latest", Prompt: "hi", JSON: true}, func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.True(t, jsonMode)
+		assert.Equal(t, []string{"{Hel", "lo"}, chunks)
+		assert.Equal(t, "{Hello", resp.Text)
+	})
+}
+
+func TestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "20")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`)
+	}))
+	defer server.Close()
+
+	p := anthropic.New(anthropic.Config{BaseURL: server.URL})
+
+	t.Run("non-200", func(t *testing.T) {
+		_, err := p.Generate(context.Background(), &provider.Request{Model: "claude-3-5-haiku-latest", Prompt: "hi"})
+		var statusErr *provider.StatusError
+		assert.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusTooManyRequests, statusErr.StatusCode)
+		assert.Equal(t, 20*time.Second, statusErr.RetryAfter)
+	})
+}
+
+func TestRefusal(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["stream"] == true {
+			fmt.Fprint(w, "data: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"refusal\"},\"usage\":{\"output_tokens\":0}}\n\n")
+			return
+		}
+		fmt.Fprint(w, `{"model":"claude-3-5-haiku-latest","content":[],"stop_reason":"refusal"}`)
+	}))
+	defer server.Close()
+
+	p := anthropic.New(anthropic.Config{BaseURL: server.URL})
+	req := &provider.Request{Model: "claude-3-5-haiku-latest", Prompt: "hi"}
+
+	_, err := p.Generate(context.Background(), req)
+	assert.ErrorIs(t, err, provider.ErrBlocked)
+	_, err = p.Stream(context.Background(), req, func(string) error { return nil })
+	assert.ErrorIs(t, err, provider.ErrBlocked)
+}
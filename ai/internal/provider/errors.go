@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrBlocked is wrapped by errors of providers that blocked the question or
+// the answer for safety; asking again will not help.
+var ErrBlocked = errors.New("blocked for safety")
+
+// StatusError is returned by HTTP based providers for non-200 responses.
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	// RetryAfter is taken from the Retry-After header, zero when absent.
+	RetryAfter time.Duration
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: non-200 response: %d, body: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// NewStatusError builds a StatusError from resp and its already read body.
+func NewStatusError(name string, resp *http.Response, body []byte) *StatusError {
+	return &StatusError{
+		Provider:   name,
+		StatusCode: resp.StatusCode,
+		RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After")),
+		Body:       string(body),
+	}
+}
+
+// ParseRetryAfter reads a Retry-After header given either in seconds or as
+// an HTTP date. It returns zero when the value is missing or invalid.
+func ParseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
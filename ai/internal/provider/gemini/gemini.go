@@ -0,0 +1,166 @@
+package gemini
+
+import (
+	"ai/internal/provider"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+type Config struct {
+	// BaseURL defaults to the endpoint of the Gemini API.
+	BaseURL string
+}
+
+type gemini struct {
+	config Config
+}
+
+func New(config Config) provider.Provider {
+	return &gemini{config}
+}
+
+func (g *gemini) Name() string {
+	return "gemini"
+}
+
+func (g *gemini) Modalities() []provider.Modality {
+	return []provider.Modality{provider.Text, provider.Image}
+}
+
+func (g *gemini) Generate(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	client, err := g.newClient(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	resp, err := newChat(client, req).SendMessage(ctx, parts(req)...)
+	if err != nil {
+		return nil, blocked(err)
+	}
+	if len(resp.Candidates) == 0 {
+		return nil, fmt.Errorf("no candidates found")
+	}
+	res := &provider.Response{
+		Text:         text(resp.Candidates[0]),
+		Model:        req.Model,
+		FinishReason: resp.Candidates[0].FinishReason.String(),
+	}
+	usage(res, resp)
+	return res, nil
+}
+
+func (g *gemini) Stream(ctx context.Context, req *provider.Request, send provider.StreamFunc) (*provider.Response, error) {
+	client, err := g.newClient(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	res := &provider.Response{Model: req.Model}
+	var answer strings.Builder
+	iter := newChat(client, req).SendMessageStream(ctx, parts(req)...)
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, blocked(err)
+		}
+		usage(res, resp)
+		if len(resp.Candidates) == 0 {
+			continue
+		}
+		candidate := resp.Candidates[0]
+		if candidate.FinishReason != genai.FinishReasonUnspecified {
+			res.FinishReason = candidate.FinishReason.String()
+		}
+		chunk := text(candidate)
+		if chunk == "" {
+			continue
+		}
+		answer.WriteString(chunk)
+		if err := send(chunk); err != nil {
+			return nil, err
+		}
+	}
+	res.Text = answer.String()
+	return res, nil
+}
+
+func (g *gemini) newClient(ctx context.Context, req *provider.Request) (*genai.Client, error) {
+	opts := []option.ClientOption{option.WithAPIKey(req.APIKey)}
+	if g.config.BaseURL != "" {
+		opts = append(opts, option.WithEndpoint(g.config.BaseURL))
+	}
+	return genai.NewClient(ctx, opts...)
+}
+
+// blocked marks the errors of blocked questions and answers with
+// provider.ErrBlocked.
+func blocked(err error) error {
+	var blockedErr *genai.BlockedError
+	if errors.As(err, &blockedErr) {
+		return fmt.Errorf("%w: %v", provider.ErrBlocked, err)
+	}
+	return err
+}
+
+func newChat(client *genai.Client, req *provider.Request) *genai.ChatSession {
+	model := client.GenerativeModel(req.Model)
+	if req.System != "" {
+		model.SystemInstruction = genai.NewUserContent(genai.Text(req.System))
+	}
+	if req.JSON {
+		model.ResponseMIMEType = "application/json"
+	}
+	chat := model.StartChat()
+	for _, m := range req.History {
+		role := "user"
+		if m.Role == "assistant" {
+			role = "model"
+		}
+		chat.History = append(chat.History, &genai.Content{Role: role, Parts: []genai.Part{genai.Text(m.Content)}})
+	}
+	return chat
+}
+
+func parts(req *provider.Request) []genai.Part {
+	parts := []genai.Part{genai.Text(req.Prompt)}
+	if len(req.Image) > 0 {
+		format := strings.TrimPrefix(req.ImageMimeType, "image/")
+		if format == "" {
+			format = "jpeg"
+		}
+		parts = append(parts, genai.ImageData(format, req.Image))
+	}
+	return parts
+}
+
+func text(candidate *genai.Candidate) string {
+	if candidate.Content == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if t, ok := part.(genai.Text); ok {
+			b.WriteString(string(t))
+		}
+	}
+	return b.String()
+}
+
+func usage(res *provider.Response, resp *genai.GenerateContentResponse) {
+	if resp.UsageMetadata == nil {
+		return
+	}
+	res.InputTokens = int(resp.UsageMetadata.PromptTokenCount)
+	res.OutputTokens = int(resp.UsageMetadata.CandidatesTokenCount)
+}
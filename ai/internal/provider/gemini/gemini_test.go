@@ -0,0 +1,140 @@
+package gemini_test
+
+import (
+	"ai/internal/health"
+	"ai/internal/provider"
+	"ai/internal/provider/gemini"
+	"ai/internal/retry"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// reply answers like the REST API, a JSON array of streamed responses with
+// enums as numbers: finishReason 1 is STOP, 3 is SAFETY.
+func reply(t *testing.T, got *map[string]interface{}, status int, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/gemini-2.0-flash:streamGenerateContent", r.URL.Path)
+		assert.Equal(t, "key", r.URL.Query().Get("key"))
+		if got != nil {
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(got))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// needsV1 skips a test of a whole reply when encoding/json is built on
+// json/v2, see jsonV2.
+func needsV1(t *testing.T) {
+	if jsonV2 {
+		t.Skip("streamed replies cannot be read with GOEXPERIMENT=jsonv2")
+	}
+}
+
+const answer = `[{"candidates": [{"content": {"role": "model", "parts": [{"text": "สวัส"}]}}], "usageMetadata": {"promptTokenCount": 3, "candidatesTokenCount": 5}},
+{"candidates": [{"content": {"role": "model", "parts": [{"text": "ดี"}]}, "finishReason": 1}], "usageMetadata": {"promptTokenCount": 3, "candidatesTokenCount": 5}}]`
+
+func TestGenerate(t *testing.T) {
+	needsV1(t)
+	var got map[string]interface{}
+	server := reply(t, &got, http.StatusOK, answer)
+	p := gemini.New(gemini.Config{BaseURL: server.URL})
+
+	t.Run("success", func(t *testing.T) {
+		resp, err := p.Generate(context.Background(), &provider.Request{
+			Model:   "gemini-2.0-flash",
+			APIKey:  "key",
+			System:  "be brief",
+			Prompt:  "hello",
+			History: []provider.Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hey"}},
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "สวัสดี", resp.Text)
+		assert.Equal(t, "gemini-2.0-flash", resp.Model)
+		assert.Equal(t, "FinishReasonStop", resp.FinishReason)
+		assert.Equal(t, 3, resp.InputTokens)
+		assert.Equal(t, 5, resp.OutputTokens)
+
+		system := got["systemInstruction"].(map[string]interface{})["parts"].([]interface{})
+		assert.Equal(t, "be brief", system[0].(map[string]interface{})["text"])
+		contents := got["contents"].([]interface{})
+		assert.Len(t, contents, 3)
+		assert.Equal(t, "model", contents[1].(map[string]interface{})["role"])
+		assert.Equal(t, "user", contents[2].(map[string]interface{})["role"])
+		assert.Nil(t, got["generationConfig"].(map[string]interface{})["responseMimeType"])
+	})
+	t.Run("json", func(t *testing.T) {
+		_, err := p.Generate(context.Background(), &provider.Request{Model: "gemini-2.0-flash", APIKey: "key", Prompt: "hello", JSON: true})
+		assert.NoError(t, err)
+		assert.Equal(t, "application/json", got["generationConfig"].(map[string]interface{})["responseMimeType"])
+	})
+	t.Run("image", func(t *testing.T) {
+		_, err := p.Generate(context.Background(), &provider.Request{Model: "gemini-2.0-flash", APIKey: "key", Prompt: "what is this?", Image: []byte("png"), ImageMimeType: "image/png"})
+		assert.NoError(t, err)
+		contents := got["contents"].([]interface{})
+		parts := contents[len(contents)-1].(map[string]interface{})["parts"].([]interface{})
+		assert.Len(t, parts, 2)
+		assert.Equal(t, "image/png", parts[1].(map[string]interface{})["inlineData"].(map[string]interface{})["mimeType"])
+	})
+}
+
+func TestStream(t *testing.T) {
+	needsV1(t)
+	server := reply(t, nil, http.StatusOK, answer)
+	p := gemini.New(gemini.Config{BaseURL: server.URL})
+	var chunks []string
+	resp, err := p.Stream(context.Background(), &provider.Request{Model: "gemini-2.0-flash", APIKey: "key", Prompt: "hello"}, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"สวัส", "ดี"}, chunks)
+	assert.Equal(t, "สวัสดี", resp.Text)
+	assert.Equal(t, "FinishReasonStop", resp.FinishReason)
+	assert.Equal(t, 5, resp.OutputTokens)
+}
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		class  retry.Class
+		// whole is set for a reply that is read to its end
+		whole bool
+	}{
+		{"quota", http.StatusTooManyRequests, `{"error": {"code": 429, "message": "Resource has been exhausted", "status": "RESOURCE_EXHAUSTED"}}`, retry.Quota, false},
+		{"key", http.StatusBadRequest, `{"error": {"code": 400, "message": "API key not valid. Please pass a valid API key.", "status": "INVALID_ARGUMENT"}}`, retry.Key, false},
+		{"server", http.StatusServiceUnavailable, `{"error": {"code": 503, "message": "The model is overloaded.", "status": "UNAVAILABLE"}}`, retry.Transient, false},
+		{"blocked question", http.StatusOK, `[{"promptFeedback": {"blockReason": 1}}]`, retry.Blocked, true},
+		{"blocked answer", http.StatusOK, `[{"candidates": [{"finishReason": 3}]}]`, retry.Blocked, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.whole {
+				needsV1(t)
+			}
+			server := reply(t, nil, tt.status, tt.body)
+			p := gemini.New(gemini.Config{BaseURL: server.URL})
+			req := &provider.Request{Model: "gemini-2.0-flash", APIKey: "key", Prompt: "hello"}
+			_, err := p.Generate(context.Background(), req)
+			assert.Equal(t, tt.class, retry.Classify(err), "generate: %v", err)
+			_, err = p.Stream(context.Background(), req, func(string) error { return nil })
+			assert.Equal(t, tt.class, retry.Classify(err), "stream: %v", err)
+			if tt.class == retry.Quota {
+				assert.True(t, health.IsQuotaError(err))
+			}
+		})
+	}
+}
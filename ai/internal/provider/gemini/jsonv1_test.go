@@ -0,0 +1,5 @@
+//go:build !goexperiment.jsonv2
+
+package gemini_test
+
+const jsonV2 = false
@@ -0,0 +1,8 @@
+//go:build goexperiment.jsonv2
+
+package gemini_test
+
+// jsonV2 is set when encoding/json is built on json/v2. Its Decoder fails
+// at the closing bracket of a streamed reply, which the REST client of genai
+// reads with gax.ProtoJSONStream, so whole replies cannot be faked.
+const jsonV2 = true
@@ -0,0 +1,256 @@
+package openai
+
+import (
+	"ai/internal/provider"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Config describes one OpenAI-compatible endpoint, e.g. ChatGPT, DeepSeek
+// or a self-hosted server.
+type Config struct {
+	Name    string
+	BaseURL string
+	// Modalities defaults to text only.
+	Modalities []provider.Modality
+	// JSONMode sends response_format json_object for JSON requests; not
+	// every self-hosted server accepts it.
+	JSONMode   bool
+	HTTPClient *http.Client
+}
+
+type openAI struct {
+	config Config
+}
+
+func New(config Config) provider.Provider {
+	if len(config.Modalities) == 0 {
+		config.Modalities = []provider.Modality{provider.Text}
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	return &openAI{config}
+}
+
+func (o *openAI) Name() string {
+	return o.config.Name
+}
+
+func (o *openAI) Modalities() []provider.Modality {
+	return o.config.Modalities
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	Stream         bool            `json:"stream,omitempty"`
+	StreamOptions  *streamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type chatMessage struct {
+	Role string `json:"role"`
+	// Content is either a string or a list of contentPart.
+	Content interface{} `json:"content"`
+}
+
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (o *openAI) Generate(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	resp, err := o.do(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var chat chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chat); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+	}
+	if len(chat.Choices) == 0 {
+		return nil, fmt.Errorf("no choices found")
+	}
+	res := &provider.Response{
+		Text:         chat.Choices[0].Message.Content,
+		Model:        modelOf(chat, req),
+		FinishReason: chat.Choices[0].FinishReason,
+	}
+	usage(res, chat)
+	if err := blocked(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (o *openAI) Stream(ctx context.Context, req *provider.Request, send provider.StreamFunc) (*provider.Response, error) {
+	resp, err := o.do(ctx, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	res := &provider.Response{Model: req.Model}
+	var answer strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+		var chunk chatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		res.Model = modelOf(chunk, req)
+		// with include_usage the last chunk has the usage and no choices
+		usage(res, chunk)
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		if chunk.Choices[0].FinishReason != "" {
+			res.FinishReason = chunk.Choices[0].FinishReason
+		}
+		if text := chunk.Choices[0].Delta.Content; text != "" {
+			answer.WriteString(text)
+			if err := send(text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	res.Text = answer.String()
+	if err := blocked(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// blocked returns provider.ErrBlocked for an answer cut off by the content
+// filter.
+func blocked(res *provider.Response) error {
+	if res.FinishReason == "content_filter" {
+		return fmt.Errorf("%w: finish reason %s", provider.ErrBlocked, res.FinishReason)
+	}
+	return nil
+}
+
+func (o *openAI) do(ctx context.Context, req *provider.Request, stream bool) (*http.Response, error) {
+	body := chatRequest{
+		Model:    req.Model,
+		Messages: messages(req),
+		Stream:   stream,
+	}
+	if stream {
+		body.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+	if req.JSON && o.config.JSONMode {
+		body.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON payload: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.config.BaseURL+"/chat/completions", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if req.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+req.APIKey)
+	}
+	resp, err := o.config.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, provider.NewStatusError(o.config.Name, resp, bodyBytes)
+	}
+	return resp, nil
+}
+
+func messages(req *provider.Request) []chatMessage {
+	var msgs []chatMessage
+	if req.System != "" {
+		msgs = append(msgs, chatMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.History {
+		msgs = append(msgs, chatMessage{Role: m.Role, Content: m.Content})
+	}
+	if len(req.Image) == 0 {
+		return append(msgs, chatMessage{Role: "user", Content: req.Prompt})
+	}
+	mimeType := req.ImageMimeType
+	if mimeType == "" {
+		mimeType = "image/jpeg"
+	}
+	return append(msgs, chatMessage{Role: "user", Content: []contentPart{
+		{Type: "text", Text: req.Prompt},
+		{Type: "image_url", ImageURL: &imageURL{URL: "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(req.Image)}},
+	}})
+}
+
+func modelOf(chat chatResponse, req *provider.Request) string {
+	if chat.Model != "" {
+		return chat.Model
+	}
+	return req.Model
+}
+
+func usage(res *provider.Response, chat chatResponse) {
+	if chat.Usage == nil {
+		return
+	}
+	res.InputTokens = chat.Usage.PromptTokens
+	res.OutputTokens = chat.Usage.CompletionTokens
+}
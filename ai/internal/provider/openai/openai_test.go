@@ -0,0 +1,106 @@
+package openai_test
+
+import (
+	"ai/internal/provider"
+	"ai/internal/provider/openai"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		fmt.Fprint(w, `{"model":"deepseek-chat","choices":[{"message":{"role":"assistant","content":"สวัสดี"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	p := openai.New(openai.Config{Name: "deepseek", BaseURL: server.URL + "/v1/"})
+
+	t.Run("success", func(t *testing.T) {
+		resp, err := p.Generate(context.Background(), &provider.Request{
+			Model:   "deepseek-chat",
+			APIKey:  "sk-test",
+			System:  "be brief",
+			Prompt:  "hello",
+			History: []provider.Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hey"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "สวัสดี", resp.Text)
+		assert.Equal(t, "deepseek-chat", resp.Model)
+		assert.Equal(t, "stop", resp.FinishReason)
+
+		assert.Equal(t, "deepseek-chat", got["model"])
+		msgs := got["messages"].([]interface{})
+		assert.Len(t, msgs, 4)
+		assert.Equal(t, "system", msgs[0].(map[string]interface{})["role"])
+		assert.Equal(t, "hello", msgs[3].(map[string]interface{})["content"])
+		assert.Nil(t, got["response_format"])
+	})
+	t.Run("json", func(t *testing.T) {
+		p := openai.New(openai.Config{Name: "deepseek", BaseURL: server.URL + "/v1/", JSONMode: true})
+		_, err := p.Generate(context.Background(), &provider.Request{Model: "deepseek-chat", APIKey: "sk-test", Prompt: "hello", JSON: true})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"type": "json_object"}, got["response_format"])
+	})
+}
+
+func TestStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, true, body["stream"])
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4\",\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4\",\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"gpt-4\",\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := openai.New(openai.Config{Name: "chatgpt", BaseURL: server.URL})
+
+	t.Run("success", func(t *testing.T) {
+		var chunks []string
+		resp, err := p.Stream(context.Background(), &provider.Request{Model: "gpt-4", Prompt: "hi"}, func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Hel", "lo"}, chunks)
+		assert.Equal(t, "Hello", resp.Text)
+		assert.Equal(t, "stop", resp.FinishReason)
+	})
+}
+
+func TestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"invalid api key"}}`)
+	}))
+	defer server.Close()
+
+	p := openai.New(openai.Config{Name: "chatgpt", BaseURL: server.URL})
+
+	t.Run("non-200", func(t *testing.T) {
+		_, err := p.Generate(context.Background(), &provider.Request{Model: "gpt-4", Prompt: "hi"})
+		assert.ErrorContains(t, err, "401")
+	})
+	t.Run("content filter", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"model":"gpt-4","choices":[{"message":{"role":"assistant","content":""},"finish_reason":"content_filter"}]}`)
+		}))
+		defer server.Close()
+		p := openai.New(openai.Config{Name: "chatgpt", BaseURL: server.URL})
+		_, err := p.Generate(context.Background(), &provider.Request{Model: "gpt-4", Prompt: "hi"})
+		assert.ErrorIs(t, err, provider.ErrBlocked)
+	})
+}
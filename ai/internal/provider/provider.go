@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Modality is a kind of input a provider can take.
+type Modality string
+
+const (
+	Text  Modality = "text"
+	Image Modality = "image"
+)
+
+// Message is one earlier turn of a conversation. Role is "user" or "assistant".
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Request is a single call to a model of a provider.
+type Request struct {
+	Model         string
+	APIKey        string
+	System        string
+	Prompt        string
+	History       []Message
+	Image         []byte
+	ImageMimeType string
+	// JSON asks for the answer as a JSON object, in the provider's JSON mode
+	// when it has one. The prompt still has to describe the object.
+	JSON bool
+}
+
+// Response is the complete answer of a model.
+type Response struct {
+	Text         string
+	Model        string
+	FinishReason string
+	// InputTokens and OutputTokens are zero when the provider does not report usage.
+	InputTokens  int
+	OutputTokens int
+}
+
+// StreamFunc receives answer chunks in the order the model produces them.
+type StreamFunc func(chunk string) error
+
+// Provider is implemented by every LLM vendor package.
+type Provider interface {
+	Name() string
+	Modalities() []Modality
+	Generate(ctx context.Context, req *Request) (*Response, error)
+	Stream(ctx context.Context, req *Request, send StreamFunc) (*Response, error)
+}
+
+// Supports reports whether p accepts input of modality m.
+func Supports(p Provider, m Modality) bool {
+	for _, modality := range p.Modalities() {
+		if modality == m {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry holds the providers known to the service by name.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+	names     []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{providers: make(map[string]Provider)}
+}
+
+// Register adds p to the registry. Names must be unique.
+func (r *Registry) Register(p Provider) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.providers[p.Name()]; ok {
+		return fmt.Errorf("provider %q already registered", p.Name())
+	}
+	r.providers[p.Name()] = p
+	r.names = append(r.names, p.Name())
+	return nil
+}
+
+func (r *Registry) Get(name string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported AI provider %q", name)
+	}
+	return p, nil
+}
+
+// Names returns the registered provider names in registration order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.names...)
+}
@@ -0,0 +1,56 @@
+package provider_test
+
+import (
+	"ai/internal/provider"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeProvider struct {
+	name       string
+	modalities []provider.Modality
+}
+
+func (f fakeProvider) Name() string                    { return f.name }
+func (f fakeProvider) Modalities() []provider.Modality { return f.modalities }
+func (f fakeProvider) Generate(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	return &provider.Response{Text: "hi", Model: req.Model}, nil
+}
+func (f fakeProvider) Stream(ctx context.Context, req *provider.Request, send provider.StreamFunc) (*provider.Response, error) {
+	return &provider.Response{Text: "hi", Model: req.Model}, send("hi")
+}
+
+func TestRegistry(t *testing.T) {
+	r := provider.NewRegistry()
+	assert.NoError(t, r.Register(fakeProvider{name: "gemini"}))
+	assert.NoError(t, r.Register(fakeProvider{name: "chatgpt"}))
+
+	t.Run("get", func(t *testing.T) {
+		p, err := r.Get("chatgpt")
+		assert.NoError(t, err)
+		assert.Equal(t, "chatgpt", p.Name())
+	})
+	t.Run("unknown", func(t *testing.T) {
+		_, err := r.Get("deepseek")
+		assert.ErrorContains(t, err, `unsupported AI provider "deepseek"`)
+	})
+	t.Run("duplicate", func(t *testing.T) {
+		assert.Error(t, r.Register(fakeProvider{name: "gemini"}))
+		assert.Equal(t, []string{"gemini", "chatgpt"}, r.Names())
+	})
+	t.Run("names are a copy", func(t *testing.T) {
+		names := r.Names()
+		names[0] = "claude"
+		assert.Equal(t, []string{"gemini", "chatgpt"}, r.Names())
+	})
+}
+
+func TestSupports(t *testing.T) {
+	vision := fakeProvider{name: "gemini", modalities: []provider.Modality{provider.Text, provider.Image}}
+	text := fakeProvider{name: "deepseek", modalities: []provider.Modality{provider.Text}}
+	assert.True(t, provider.Supports(vision, provider.Image))
+	assert.False(t, provider.Supports(text, provider.Image))
+	assert.True(t, provider.Supports(text, provider.Text))
+}
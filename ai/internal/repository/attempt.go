@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"ai/api/pb"
+	"ai/internal/entity"
+	"ai/internal/health"
+	"ai/internal/retry"
+	"context"
+	"fmt"
+	"time"
+)
+
+// tries is what has been tried for one question.
+type tries struct {
+	// failed are the models and keys not to try again.
+	failed   map[string]bool
+	attempts []entity.Attempt
+	// retries counts the retries of the model in use.
+	retries int
+}
+
+func newTries() *tries {
+	return &tries{failed: make(map[string]bool)}
+}
+
+func (t *tries) record(ai string, model string, key string, start time.Time, err error, class retry.Class, action retry.Action) {
+	attempt := entity.Attempt{
+		Provider: ai,
+		Model:    model,
+		Duration: time.Since(start),
+		Class:    string(class),
+		Action:   string(action),
+	}
+	if key != "" {
+		attempt.Key = health.Fingerprint(key)
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	t.attempts = append(t.attempts, attempt)
+}
+
+// succeeded records a call that gave an answer.
+func (a *aiRepository) succeeded(t *tries, ai string, model string, key string, start time.Time, resp *entity.AiAnswer) {
+	t.record(ai, model, key, start, nil, "", "")
+	t.retries = 0
+	a.keys.Success(ai, key, time.Since(start), resp.InputTokens, resp.OutputTokens)
+}
+
+// failed records a call that failed and acts on it as the retry policy
+// says. It returns again when the same model is to be called again, after
+// waiting for the backoff, or an error to give up with.
+func (a *aiRepository) failed(ctx context.Context, t *tries, ai string, model string, key string, start time.Time, err error) (again bool, stop error) {
+	fmt.Println(err)
+	if ctx.Err() != nil {
+		// out of time, not the model's fault
+		t.record(ai, model, key, start, err, retry.Classify(err), retry.Stop)
+		return false, ctx.Err()
+	}
+	class := retry.Classify(err)
+	action := a.retry.Decide(class, t.retries)
+	if len(t.attempts)+1 >= a.retry.MaxAttempts {
+		if action == retry.Retry || action == retry.CoolDown {
+			// the failure still counts for the requests to come
+			a.reportFailure(ctx, t.failed, ai, model, key, err)
+		}
+		action = retry.Stop
+	}
+	t.record(ai, model, key, start, err, class, action)
+	switch action {
+	case retry.Retry:
+		wait := a.retry.Backoff(t.retries)
+		t.retries++
+		if err := retry.Sleep(ctx, wait); err != nil {
+			return false, err
+		}
+		return true, nil
+	case retry.Skip:
+		t.failed[modelKey(ai, model)] = true
+	case retry.Stop:
+		return false, fmt.Errorf("gave up after %d attempts: %w", len(t.attempts), err)
+	default:
+		a.reportFailure(ctx, t.failed, ai, model, key, err)
+	}
+	t.retries = 0
+	return false, nil
+}
+
+// toPbAttempts returns the attempts when the request asked for them.
+func toPbAttempts(debug bool, attempts []entity.Attempt) []*pb.Attempt {
+	if !debug {
+		return nil
+	}
+	var res []*pb.Attempt
+	for _, a := range attempts {
+		res = append(res, &pb.Attempt{
+			Provider:   a.Provider,
+			Model:      a.Model,
+			Key:        a.Key,
+			DurationMs: a.Duration.Milliseconds(),
+			Error:      a.Error,
+			Class:      a.Class,
+			Action:     a.Action,
+		})
+	}
+	return res
+}
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"ai/api/pb"
+	"ai/internal/entity"
+	"ai/internal/grounding"
+	"ai/internal/health"
+	"ai/internal/keypool"
+	"ai/internal/prompt"
+	"ai/internal/provider"
+	"ai/internal/retry"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeProvider fails with the errors of model in turn, then answers.
+type fakeProvider struct {
+	name   string
+	errors map[string][]error
+	calls  []string
+}
+
+func (f *fakeProvider) Name() string                    { return f.name }
+func (f *fakeProvider) Modalities() []provider.Modality { return []provider.Modality{provider.Text} }
+
+func (f *fakeProvider) Generate(ctx context.Context, req *provider.Request) (*provider.Response, error) {
+	f.calls = append(f.calls, req.Model+" "+req.APIKey)
+	if errs := f.errors[req.Model]; len(errs) > 0 {
+		f.errors[req.Model] = errs[1:]
+		return nil, errs[0]
+	}
+	return &provider.Response{Text: "The office opens at 9.", Model: req.Model}, nil
+}
+
+func (f *fakeProvider) Stream(ctx context.Context, req *provider.Request, send provider.StreamFunc) (*provider.Response, error) {
+	resp, err := f.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return resp, send(resp.Text)
+}
+
+func statusError(code int) error {
+	return &provider.StatusError{Provider: "gemini", StatusCode: code}
+}
+
+func newTestRepository(t *testing.T, p provider.Provider, policy retry.Policy) *aiRepository {
+	providers := provider.NewRegistry()
+	assert.NoError(t, providers.Register(p))
+	ais := map[string]entity.Ai{p.Name(): {Keys: []string{"key1", "key2"}, Models: []string{"flash", "pro"}}}
+	keys := keypool.New(keypool.RoundRobin)
+	keys.Add(p.Name(), ais[p.Name()].Keys, 0)
+	prompts := prompt.NewSet(prompt.Config{Dir: "../../prompts"})
+	assert.NoError(t, prompts.Reload())
+	policy.Rand = func() float64 { return 0 }
+	registry := health.NewRegistry(health.NewMemoryStore(), health.Config{})
+	return NewAiRepository(providers, ais, nil, registry, keys, 0, nil, prompts, nil, nil, Timeouts{}, policy).(*aiRepository)
+}
+
+func TestAttempts(t *testing.T) {
+	ctx := context.Background()
+	question := &entity.AiRequest{Question: "When does the office open?", Lang: "en"}
+
+	t.Run("retry then answer", func(t *testing.T) {
+		p := &fakeProvider{name: "gemini", errors: map[string][]error{"flash": {statusError(http.StatusServiceUnavailable)}}}
+		a := newTestRepository(t, p, retry.Policy{})
+		tries := newTries()
+		resp, err := a.generateWith(ctx, question, prompt.Data{}, nil, tries)
+		assert.NoError(t, err)
+		assert.Equal(t, "flash", resp.Model)
+		assert.Equal(t, []string{"flash key1", "flash key1"}, p.calls)
+		assert.Len(t, tries.attempts, 2)
+		assert.Equal(t, string(retry.Transient), tries.attempts[0].Class)
+		assert.Equal(t, string(retry.Retry), tries.attempts[0].Action)
+		assert.Equal(t, health.Fingerprint("key1"), tries.attempts[0].Key)
+		assert.Empty(t, tries.attempts[1].Error)
+		assert.True(t, a.health.ModelAvailable(ctx, "gemini", "flash"))
+	})
+	t.Run("cool down the key", func(t *testing.T) {
+		p := &fakeProvider{name: "gemini", errors: map[string][]error{"flash": {statusError(http.StatusTooManyRequests)}}}
+		a := newTestRepository(t, p, retry.Policy{})
+		tries := newTries()
+		_, err := a.generateWith(ctx, question, prompt.Data{}, nil, tries)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"flash key1", "flash key2"}, p.calls)
+		assert.Equal(t, string(retry.CoolDown), tries.attempts[0].Action)
+		assert.False(t, a.health.KeyAvailable(ctx, "gemini", "key1"))
+		assert.True(t, a.health.ModelAvailable(ctx, "gemini", "flash"))
+	})
+	t.Run("skip an invalid request", func(t *testing.T) {
+		p := &fakeProvider{name: "gemini", errors: map[string][]error{"flash": {statusError(http.StatusBadRequest)}}}
+		a := newTestRepository(t, p, retry.Policy{})
+		tries := newTries()
+		resp, err := a.generateWith(ctx, question, prompt.Data{}, nil, tries)
+		assert.NoError(t, err)
+		assert.Equal(t, "pro", resp.Model)
+		assert.Equal(t, string(retry.Skip), tries.attempts[0].Action)
+		// skipped for this question only
+		assert.True(t, a.health.ModelAvailable(ctx, "gemini", "flash"))
+	})
+	t.Run("stop when blocked", func(t *testing.T) {
+		blocked := fmt.Errorf("%w: candidate: FinishReasonSafety", provider.ErrBlocked)
+		p := &fakeProvider{name: "gemini", errors: map[string][]error{"flash": {blocked}}}
+		a := newTestRepository(t, p, retry.Policy{})
+		tries := newTries()
+		resp, err := a.generateWith(ctx, question, prompt.Data{}, nil, tries)
+		assert.NoError(t, err)
+		assert.Equal(t, entity.OutcomeRefused, resp.Outcome)
+		assert.Equal(t, "Sorry, this question cannot be answered.", resp.Answer)
+		assert.Len(t, p.calls, 1)
+		assert.Equal(t, string(retry.Stop), tries.attempts[0].Action)
+		assert.True(t, a.health.ModelAvailable(ctx, "gemini", "flash"))
+	})
+	t.Run("max attempts", func(t *testing.T) {
+		down := statusError(http.StatusInternalServerError)
+		p := &fakeProvider{name: "gemini", errors: map[string][]error{"flash": {down}, "pro": {down}}}
+		a := newTestRepository(t, p, retry.Policy{MaxAttempts: 2, Retries: -1})
+		tries := newTries()
+		_, err := a.generateWith(ctx, question, prompt.Data{}, nil, tries)
+		assert.ErrorContains(t, err, "gave up after 2 attempts")
+		assert.Equal(t, []string{"flash key1", "pro key2"}, p.calls)
+		assert.Equal(t, string(retry.CoolDown), tries.attempts[0].Action)
+		assert.Equal(t, string(retry.Stop), tries.attempts[1].Action)
+		// the last failure is reported too
+		assert.False(t, a.health.ModelAvailable(ctx, "gemini", "flash"))
+		assert.False(t, a.health.ModelAvailable(ctx, "gemini", "pro"))
+	})
+	t.Run("out of time", func(t *testing.T) {
+		a := newTestRepository(t, &fakeProvider{name: "gemini"}, retry.Policy{})
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		tries := newTries()
+		again, err := a.failed(ctx, tries, "gemini", "flash", "key1", time.Now(), fmt.Errorf("read: %w", context.Canceled))
+		assert.False(t, again)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, string(retry.Stop), tries.attempts[0].Action)
+		// not the model's fault
+		assert.True(t, a.health.ModelAvailable(ctx, "gemini", "flash"))
+	})
+}
+
+func TestBlocked(t *testing.T) {
+	ctx := context.Background()
+	req := &pb.AiRequest{Question: "How do I pick a lock?", Lang: "en", Debug: true}
+	blocked := func() *fakeProvider {
+		return &fakeProvider{name: "gemini", errors: map[string][]error{"flash": {fmt.Errorf("%w: candidate: FinishReasonSafety", provider.ErrBlocked)}}}
+	}
+
+	t.Run("ask", func(t *testing.T) {
+		a := newTestRepository(t, blocked(), retry.Policy{})
+		withKnowledge(t, a, map[string]string{"hours.md": "The office opens at 9."})
+		resp, err := a.Ask(ctx, req)
+		assert.NoError(t, err)
+		assert.Equal(t, entity.OutcomeRefused, resp.Outcome)
+		assert.Equal(t, "Sorry, this question cannot be answered.", resp.Answer)
+		assert.Len(t, resp.Attempts, 1)
+	})
+	t.Run("ask stream", func(t *testing.T) {
+		a := newTestRepository(t, &streamingProvider{fakeProvider: blocked()}, retry.Policy{})
+		withKnowledge(t, a, map[string]string{"hours.md": "The office opens at 9."})
+		var sent []*pb.AiStreamResponse
+		err := a.AskStream(ctx, req, func(resp *pb.AiStreamResponse) error {
+			sent = append(sent, resp)
+			return nil
+		})
+		assert.NoError(t, err)
+		if assert.Len(t, sent, 2) {
+			assert.Equal(t, "Sorry, this question cannot be answered.", sent[0].Chunk)
+			assert.True(t, sent[1].Done)
+			assert.Equal(t, entity.OutcomeRefused, sent[1].Outcome)
+			assert.Equal(t, "Sorry, this question cannot be answered.", sent[1].AnswerEn)
+		}
+	})
+}
+
+func TestGroundedAnswer(t *testing.T) {
+	ctx := context.Background()
+	question := &entity.AiRequest{Question: "Where is EN16101?", Lang: "en"}
+	// search fails with the status of a key, or answers
+	search := func(t *testing.T, statuses map[string]int) *grounding.Client {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if status := statuses[r.Header.Get("x-goog-api-key")]; status != 0 {
+				w.WriteHeader(status)
+				return
+			}
+			w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "English: Near building 50"}]}, "finishReason": "STOP"}]}`))
+		}))
+		t.Cleanup(server.Close)
+		return grounding.New(grounding.Config{BaseURL: server.URL, Model: "flash"})
+	}
+
+	t.Run("next key after a quota error", func(t *testing.T) {
+		a := newTestRepository(t, &fakeProvider{name: "gemini"}, retry.Policy{})
+		a.grounding = search(t, map[string]int{"key1": http.StatusTooManyRequests})
+		tries := newTries()
+		answer := &entity.AiAnswer{}
+		assert.NoError(t, a.groundedAnswer(ctx, question, answer, tries))
+		assert.Equal(t, "Near building 50", answer.Answer)
+		assert.Equal(t, entity.SourceSearch, answer.Source)
+		if assert.Len(t, tries.attempts, 2) {
+			assert.Equal(t, string(retry.CoolDown), tries.attempts[0].Action)
+			assert.Equal(t, health.Fingerprint("key2"), tries.attempts[1].Key)
+			assert.Empty(t, tries.attempts[1].Error)
+		}
+		assert.False(t, a.health.KeyAvailable(ctx, "gemini", "key1"))
+	})
+	t.Run("failure reported", func(t *testing.T) {
+		a := newTestRepository(t, &fakeProvider{name: "gemini"}, retry.Policy{Retries: -1})
+		a.grounding = search(t, map[string]int{"key1": http.StatusInternalServerError, "key2": http.StatusInternalServerError})
+		tries := newTries()
+		assert.Error(t, a.groundedAnswer(ctx, question, &entity.AiAnswer{}, tries))
+		assert.Len(t, tries.attempts, 1)
+		assert.False(t, a.health.ModelAvailable(ctx, "gemini", "flash"))
+	})
+}
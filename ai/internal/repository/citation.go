@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"ai/api/pb"
+	"ai/internal/entity"
+	"ai/internal/grounding"
+	"ai/internal/retrieval"
+	"strings"
+)
+
+// citedIn returns the knowledge the answer cites by label, e.g.
+// "(CLI-1.pdf p.3)". Models do not always cite, then everything the answer
+// was given from is returned.
+func citedIn(answer string, knowledge []retrieval.Chunk) []entity.Citation {
+	var cited, all []entity.Citation
+	seen := map[string]bool{}
+	for _, c := range knowledge {
+		label := c.Citation()
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		citation := entity.Citation{Source: c.Source, EntryID: c.ID, Page: c.Page, Title: c.Title}
+		all = append(all, citation)
+		if strings.Contains(answer, label) {
+			cited = append(cited, citation)
+		}
+	}
+	if len(cited) > 0 {
+		return cited
+	}
+	return all
+}
+
+// webCitations cites the pages a grounded Gemini answer was found on.
+func webCitations(sources []grounding.Web) []entity.Citation {
+	var citations []entity.Citation
+	for _, w := range sources {
+		citations = append(citations, entity.Citation{URL: w.URI, Title: w.Title})
+	}
+	return citations
+}
+
+func toPbCitations(citations []entity.Citation) []*pb.Citation {
+	var res []*pb.Citation
+	for _, c := range citations {
+		res = append(res, &pb.Citation{
+			Source:  c.Source,
+			EntryId: c.EntryID,
+			Page:    int32(c.Page),
+			Title:   c.Title,
+			Url:     c.URL,
+		})
+	}
+	return res
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"ai/internal/entity"
+	"ai/internal/grounding"
+	"ai/internal/retrieval"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCitedIn(t *testing.T) {
+	knowledge := []retrieval.Chunk{
+		{Source: "CLI-1.pdf", Page: 3, Title: "CLI-1"},
+		{Source: "CLI-1.pdf", Page: 3, Title: "CLI-1"},
+		{Source: "question.json", ID: "leave", Title: "การลาพักการศึกษา"},
+		{Source: "hours.md", Title: "hours"},
+	}
+	pdf := entity.Citation{Source: "CLI-1.pdf", Page: 3, Title: "CLI-1"}
+	faq := entity.Citation{Source: "question.json", EntryID: "leave", Title: "การลาพักการศึกษา"}
+	hours := entity.Citation{Source: "hours.md", Title: "hours"}
+
+	t.Run("cited by label", func(t *testing.T) {
+		answer := "Run ls (CLI-1.pdf p.3), see also question.json#leave."
+		assert.Equal(t, []entity.Citation{pdf, faq}, citedIn(answer, knowledge))
+	})
+	t.Run("nothing cited", func(t *testing.T) {
+		assert.Equal(t, []entity.Citation{pdf, faq, hours}, citedIn("Run ls.", knowledge))
+	})
+	t.Run("no knowledge", func(t *testing.T) {
+		assert.Empty(t, citedIn("Run ls.", nil))
+	})
+}
+
+func TestWebCitations(t *testing.T) {
+	sources := []grounding.Web{{URI: "https://reg.kku.ac.th", Title: "reg.kku.ac.th"}, {URI: "https://kku.ac.th"}}
+	assert.Equal(t, []entity.Citation{
+		{URL: "https://reg.kku.ac.th", Title: "reg.kku.ac.th"},
+		{URL: "https://kku.ac.th"},
+	}, webCitations(sources))
+	assert.Empty(t, webCitations(nil))
+}
+
+func TestToPbCitations(t *testing.T) {
+	res := toPbCitations([]entity.Citation{{Source: "CLI-1.pdf", Page: 3}, {URL: "https://kku.ac.th"}})
+	assert.Len(t, res, 2)
+	assert.Equal(t, "CLI-1.pdf", res[0].GetSource())
+	assert.Equal(t, int32(3), res[0].GetPage())
+	assert.Equal(t, "https://kku.ac.th", res[1].GetUrl())
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"ai/internal/entity"
+	"ai/internal/provider"
+	"unicode/utf8"
+)
+
+// defaultHistoryBudget is the number of tokens the earlier turns of a
+// conversation may take when ai.history.token_budget is not set.
+const defaultHistoryBudget = 2000
+
+// estimateTokens roughly counts the tokens of s without a tokenizer. English
+// averages about four characters a token, Thai and other scripts outside
+// ASCII close to one.
+func estimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// trimHistory keeps the newest turns of history that fit in budget tokens.
+// The kept turns always start with a user turn so every provider accepts them.
+func trimHistory(history []entity.Message, budget int) []entity.Message {
+	start := len(history)
+	used := 0
+	for start > 0 {
+		tokens := estimateTokens(history[start-1].Content)
+		if used+tokens > budget {
+			break
+		}
+		used += tokens
+		start--
+	}
+	for start < len(history) && history[start].Role != "user" {
+		start++
+	}
+	return history[start:]
+}
+
+func toMessages(history []entity.Message) []provider.Message {
+	messages := make([]provider.Message, 0, len(history))
+	for _, m := range history {
+		if m.Content == "" {
+			continue
+		}
+		role := "user"
+		if m.Role == "assistant" {
+			role = "assistant"
+		}
+		messages = append(messages, provider.Message{Role: role, Content: m.Content})
+	}
+	return messages
+}
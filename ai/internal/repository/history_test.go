@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"ai/internal/entity"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, estimateTokens(""))
+	assert.Equal(t, 2, estimateTokens("hello"))
+	assert.Equal(t, 6, estimateTokens("สวัสดี"))
+}
+
+func TestTrimHistory(t *testing.T) {
+	turn := func(role string, tokens int) entity.Message {
+		return entity.Message{Role: role, Content: strings.Repeat("abcd", tokens)}
+	}
+	history := []entity.Message{
+		turn("user", 10), turn("assistant", 10),
+		turn("user", 10), turn("assistant", 10),
+		turn("user", 10), turn("assistant", 10),
+	}
+
+	t.Run("all fit", func(t *testing.T) {
+		assert.Equal(t, history, trimHistory(history, 60))
+	})
+	t.Run("newest turns kept", func(t *testing.T) {
+		assert.Equal(t, history[2:], trimHistory(history, 45))
+	})
+	t.Run("starts with a user turn", func(t *testing.T) {
+		// the budget would take the last three turns, from an assistant turn
+		assert.Equal(t, history[4:], trimHistory(history, 30))
+	})
+	t.Run("nothing fits", func(t *testing.T) {
+		assert.Empty(t, trimHistory(history, 5))
+		assert.Empty(t, trimHistory(nil, 100))
+	})
+	t.Run("a long turn ends the history", func(t *testing.T) {
+		long := []entity.Message{turn("user", 10), turn("assistant", 10), turn("user", 100), turn("assistant", 10)}
+		assert.Empty(t, trimHistory(long, 50))
+	})
+}
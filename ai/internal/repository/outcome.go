@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"ai/internal/bilingual"
+	"ai/internal/entity"
+	"encoding/json"
+	"strings"
+)
+
+// Replies in text start with one of these markers when the model does not
+// answer; JSON replies set "outcome" instead.
+const (
+	unknownMarker = "[UNKNOWN]"
+	refusedMarker = "[REFUSED]"
+)
+
+var markers = map[string]string{
+	unknownMarker: entity.OutcomeUnknown,
+	refusedMarker: entity.OutcomeRefused,
+}
+
+// textOutcome tells the outcome of a text reply from its start and returns
+// the reply without the marker. decided is false while the reply could
+// still turn into a marker, so a stream holds it back until then.
+func textOutcome(text string) (outcome string, rest string, decided bool) {
+	trimmed := strings.TrimSpace(text)
+	for marker, outcome := range markers {
+		if strings.HasPrefix(trimmed, marker) {
+			return outcome, strings.TrimSpace(strings.TrimPrefix(trimmed, marker)), true
+		}
+		if strings.HasPrefix(marker, trimmed) {
+			return "", text, false
+		}
+	}
+	return entity.OutcomeAnswered, text, true
+}
+
+// parseReply returns the outcome and the answer of a whole reply, either a
+// JSON object or text.
+func parseReply(text string) (string, bilingual.Answer) {
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		var reply struct {
+			Outcome string `json:"outcome"`
+		}
+		if err := json.Unmarshal([]byte(text[start:end+1]), &reply); err == nil {
+			switch reply.Outcome {
+			case entity.OutcomeAnswered, entity.OutcomeUnknown, entity.OutcomeRefused:
+				return reply.Outcome, bilingual.Parse(text)
+			}
+		}
+	}
+	outcome, rest, _ := textOutcome(text)
+	return outcome, bilingual.Parse(rest)
+}
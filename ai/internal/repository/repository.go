@@ -2,252 +2,516 @@ package repository
 
 import (
 	"ai/api/pb"
+	"ai/internal/bilingual"
 	"ai/internal/entity"
-	"bytes"
+	"ai/internal/grounding"
+	"ai/internal/health"
+	"ai/internal/keypool"
+	"ai/internal/knowledge"
+	"ai/internal/prompt"
+	"ai/internal/provider"
+	"ai/internal/retrieval"
+	"ai/internal/retry"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"strings"
 	"time"
-
-	"github.com/google/generative-ai-go/genai"
-	"golang.org/x/exp/rand"
-	"google.golang.org/api/option"
 )
 
 type AiRepository interface {
-	Ask(*pb.AiRequest) (*pb.AiResponse, error)
+	Ask(context.Context, *pb.AiRequest) (*pb.AiResponse, error)
+	AskStream(context.Context, *pb.AiRequest, func(*pb.AiStreamResponse) error) error
+	GetHealth(context.Context) (*pb.HealthResponse, error)
 }
 type aiRepository struct {
-	ChatGPTKey  entity.Ai
-	ClaudeKey   entity.Ai
-	GeminiKey   entity.Ai
-	DeepSeekKey entity.Ai
+	providers *provider.Registry
+	ais       map[string]entity.Ai
+	// chain is the order in which providers are tried.
+	chain  []string
+	health *health.Registry
+	keys   *keypool.Pool
+	// historyBudget is the number of tokens earlier turns may take.
+	historyBudget int
+	knowledge     *retrieval.Retriever
+	prompts       *prompt.Set
+	grounding     *grounding.Client
+	// escalation lists how unknown answers are followed up, in order.
+	escalation []string
+	timeouts   Timeouts
+	retry      retry.Policy
 }
 
-func NewAiRepository(ChatGPTKey entity.Ai, ClaudeKey entity.Ai, GeminiKey entity.Ai, DeepSeekKey entity.Ai) AiRepository {
-	return &aiRepository{ChatGPTKey, ClaudeKey, GeminiKey, DeepSeekKey}
+func NewAiRepository(providers *provider.Registry, ais map[string]entity.Ai, chain []string, health *health.Registry, keys *keypool.Pool, historyBudget int, knowledge *retrieval.Retriever, prompts *prompt.Set, grounding *grounding.Client, escalation []string, timeouts Timeouts, policy retry.Policy) AiRepository {
+	if len(chain) == 0 {
+		chain = providers.Names()
+	}
+	if historyBudget <= 0 {
+		historyBudget = defaultHistoryBudget
+	}
+	return &aiRepository{providers, ais, chain, health, keys, historyBudget, knowledge, prompts, grounding, escalation, timeouts.withDefaults(), policy.WithDefaults()}
 }
-func randomAPIKey(options []string) string {
-	rand.Seed(uint64(time.Now().UnixNano()))
-	return options[rand.Intn(len(options))]
+
+// chooseKey asks the key pool for a key of ai that is neither cooling down
+// nor already failed in this request. Self-hosted OpenAI-compatible servers
+// may have no keys at all, then "" is returned with ok set.
+func (a *aiRepository) chooseKey(ctx context.Context, ai string, failed map[string]bool) (key string, ok bool) {
+	if len(a.ais[ai].Keys) == 0 {
+		return "", true
+	}
+	return a.keys.Pick(ai, func(k string) bool {
+		return !failed[keyKey(ai, k)] && a.health.KeyAvailable(ctx, ai, k)
+	})
 }
-func generateContentWithFallback(ctx context.Context, prompt string, imageData []byte, ai string, aiKeys []string, models []string) (*entity.AiAnswer, error) {
-	disabledModels := make(map[string]time.Time)
-	var resp *entity.AiAnswer
-	var err error
-	for {
-		chosenModel := ""
-		for _, m := range models {
-			if t, ok := disabledModels[m]; ok && time.Now().Before(t) {
+
+// chooseModel walks the chain in order and returns the first provider, model
+// and key that are usable. Providers that are not registered, have no models
+// configured or cannot take an image when one is given are skipped, and so
+// are models and keys that are cooling down or already failed in this request.
+// A key is only picked once a model of its provider is usable.
+func (a *aiRepository) chooseModel(ctx context.Context, failed map[string]bool, hasImage bool) (provider.Provider, string, string) {
+	for _, ai := range a.chain {
+		p, err := a.providers.Get(ai)
+		if err != nil {
+			continue
+		}
+		if hasImage && !provider.Supports(p, provider.Image) {
+			continue
+		}
+		for _, m := range a.ais[ai].Models {
+			if failed[modelKey(ai, m)] || !a.health.ModelAvailable(ctx, ai, m) {
 				continue
 			}
-			chosenModel = m
-			break
+			key, ok := a.chooseKey(ctx, ai, failed)
+			if !ok {
+				break
+			}
+			return p, m, key
+		}
+	}
+	return nil, "", ""
+}
+func modelKey(ai string, model string) string {
+	return ai + "/" + model
+}
+func keyKey(ai string, key string) string {
+	return ai + "#" + health.Fingerprint(key)
+}
+
+// reportFailure puts the model or key that failed into the shared health
+// registry and remembers it for the rest of this request, so the loop ends
+// even if the registry store cannot be written.
+func (a *aiRepository) reportFailure(ctx context.Context, failed map[string]bool, ai string, model string, key string, err error) {
+	a.keys.Failure(ai, key, health.IsQuotaError(err))
+	cooldown := a.health.Report(ctx, ai, model, key, err)
+	if cooldown.Key != "" {
+		failed[keyKey(ai, key)] = true
+	} else {
+		failed[modelKey(ai, model)] = true
+	}
+}
+
+func (a *aiRepository) generateContentWithFallback(ctx context.Context, question *entity.AiRequest) (*entity.AiAnswer, error) {
+	knowledge, chunks, err := a.searchKnowledge(ctx, question)
+	if err != nil {
+		return nil, err
+	}
+	data := prompt.Data{Lang: question.Lang, JSON: true, Knowledge: knowledge}
+	t := newTries()
+	resp, err := a.generateWith(ctx, question, data, chunks, t)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Outcome == entity.OutcomeUnknown {
+		if resp, err = a.escalate(ctx, question, data, chunks, t, resp); err != nil {
+			return nil, err
+		}
+	}
+	resp.Attempts = t.attempts
+	return resp, nil
+}
+
+// generateWith walks the chain until a model replies, skipping the models
+// and keys that failed before and acting on new failures as the retry
+// policy says. It gives up when ctx is done.
+func (a *aiRepository) generateWith(ctx context.Context, question *entity.AiRequest, data prompt.Data, chunks []retrieval.Chunk, t *tries) (*entity.AiAnswer, error) {
+	var p provider.Provider
+	var chosenModel, apiKey string
+	again := false
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !again {
+			p, chosenModel, apiKey = a.chooseModel(ctx, t.failed, len(question.Image) > 0)
 		}
-		if chosenModel == "" {
-			for model, t := range disabledModels {
-				remaining := time.Until(t)
-				fmt.Printf("Model %s: %v,%v,%v remaining\n", model, remaining.Hours(), remaining.Minutes(), remaining.Seconds())
+		if p == nil {
+			if cooldowns, err := a.health.List(ctx); err == nil {
+				for _, c := range cooldowns {
+					fmt.Printf("%s %s%s: %v remaining\n", c.Provider, c.Model, c.Key, time.Until(c.Until).Round(time.Second))
+				}
 			}
-			return nil, fmt.Errorf("all models are disabled")
+			return nil, fmt.Errorf("all providers are disabled")
 		}
-		apiKey := randomAPIKey(aiKeys)
-		resp, err = generate(ctx, ai, prompt, imageData, apiKey, chosenModel)
+		system, version, err := a.prompts.Render(prompt.System, p.Name(), data)
 		if err != nil {
-			fmt.Println(err)
-			disabledModels[chosenModel] = time.Now().Add(time.Second * 30)
+			return nil, err
+		}
+		start := time.Now()
+		resp, err := a.generate(ctx, p, question, system, apiKey, chosenModel)
+		if err != nil {
+			if again, err = a.failed(ctx, t, p.Name(), chosenModel, apiKey, start, err); err != nil {
+				if errors.Is(err, provider.ErrBlocked) {
+					return a.blocked(p.Name(), chosenModel, question.Lang)
+				}
+				return nil, err
+			}
 			continue
 		}
-		break
+		a.succeeded(t, p.Name(), chosenModel, apiKey, start, resp)
+		resp.PromptVersion = version
+		if resp.Outcome == entity.OutcomeAnswered {
+			resp.Citations = citedIn(resp.Answer, chunks)
+		}
+		return resp, nil
 	}
-	return resp, nil
 }
 
-func generate(ctx context.Context, ai string, prompt string, imageData []byte, apiKey string, model string) (*entity.AiAnswer, error) {
-	var aiAnswer = &entity.AiAnswer{}
-	jsonFilePath := "./assets/question.json"
-	data, err := ioutil.ReadFile(jsonFilePath)
+// streamContentWithFallback walks the chain like generateContentWithFallback,
+// but only while nothing has been sent yet; once the client has seen part of
+// an answer a failure is returned instead of starting over on another model.
+// Nothing is sent of an unknown answer, the escalated one is sent whole, and
+// neither of a blocked one, the refused notice is sent instead.
+func (a *aiRepository) streamContentWithFallback(ctx context.Context, question *entity.AiRequest, send provider.StreamFunc) (*entity.AiAnswer, error) {
+	knowledge, chunks, err := a.searchKnowledge(ctx, question)
 	if err != nil {
-		fmt.Println(err)
-		return nil, fmt.Errorf("failed to read json file: %w", err)
-	}
-	//If you don't know just say "Don't know" don't need Thai just the word "Don't know".
-	var systemPrompt = `You are the KKU Information AI. You have access to a JSON file containing detailed and up-to-date information about Khon Kaen University (KKU). Your task is to answer any user query using only the data provided in the JSON file. **However, if the queried information is not found in the JSON file, you must search for the information from reliable sources to answer the question.** Before providing your answer, verify the credibility of the information by checking if multiple reputable sites refer to it. Do not provide random or inaccurate answers. If the search does not yield any results or the information is unavailable in your model, clearly respond that the information is unavailable.
-	You must always provide your answers in both Thai and English. Ensure that your responses are precise, fact-based, and directly address the user's question. Do not include any extraneous information beyond what is necessary to answer the query.
-	If you don't know just say "Don't know" don't need Thai just the word "Don't know".
-	For example:
-	User Query: Where are EN16101?
-	Your Response:
-	ภาษาไทย: อยู่ข้างตึก 50
-	English: Near 50th anniversary building
-
-	Important rules:
-	1. Always use the data from the JSON file to answer the question, if the information is available there.
-	2. **If the required information is not found in the JSON file, search for the information from reliable sources.**
-	3. Before answering, verify the credibility of the information by checking if multiple reputable sites confirm it.
-	4. Do not provide random or inaccurate information.
-	5. If the search does not yield any results or the information is unavailable, clearly state that the information is unavailable.
-	6. Keep the response strictly limited to answering the user’s query without additional commentary or unrelated details.
-	7. Answer in both languages (Thai and English) in every response.
-	8. Must answer with raw text, do not include any HTML tags or formatting.`
-	fullPrompt := "More Data:\n" + string(data) + "\n\nSystem Query:\n" + systemPrompt + "\n\nUser Query:\n" + prompt
-
-	switch ai {
-	case "gemini":
-		var resp *genai.GenerateContentResponse
-		client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+		return nil, err
+	}
+	data := prompt.Data{Lang: question.Lang, JSON: false, Knowledge: knowledge}
+	t := newTries()
+	var p provider.Provider
+	var chosenModel, apiKey string
+	again := false
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !again {
+			p, chosenModel, apiKey = a.chooseModel(ctx, t.failed, len(question.Image) > 0)
+		}
+		if p == nil {
+			return nil, fmt.Errorf("all providers are disabled")
+		}
+		system, version, err := a.prompts.Render(prompt.System, p.Name(), data)
 		if err != nil {
-			fmt.Println(err)
 			return nil, err
 		}
-		defer client.Close()
-
-		generative := client.GenerativeModel(model)
-		if len(imageData) > 0 {
-			resp, err = generative.GenerateContent(ctx, genai.Text(fullPrompt), genai.ImageData("jpeg", imageData))
-			if err != nil {
+		sent := false
+		start := time.Now()
+		resp, err := a.stream(ctx, p, question, system, apiKey, chosenModel, func(chunk string) error {
+			sent = true
+			return send(chunk)
+		})
+		if err != nil {
+			if sent {
 				fmt.Println(err)
 				return nil, err
 			}
-		} else {
-			resp, err = generative.GenerateContent(ctx, genai.Text(fullPrompt))
-			if err != nil {
-				fmt.Println(err)
-				return nil, err
+			if again, err = a.failed(ctx, t, p.Name(), chosenModel, apiKey, start, err); err != nil {
+				if !errors.Is(err, provider.ErrBlocked) {
+					return nil, err
+				}
+				if resp, err = a.blocked(p.Name(), chosenModel, question.Lang); err != nil {
+					return nil, err
+				}
+				if err := send(resp.Answer); err != nil {
+					return nil, err
+				}
+				resp.Attempts = t.attempts
+				return resp, nil
 			}
+			continue
+		}
+		a.succeeded(t, p.Name(), chosenModel, apiKey, start, resp)
+		resp.PromptVersion = version
+		if resp.Outcome == entity.OutcomeAnswered {
+			resp.Citations = citedIn(resp.Answer, chunks)
 		}
-		aiAnswer.Answer = fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0])
-		if strings.Contains(aiAnswer.Answer, "Don't know") {
-			// resp, err = dontKnow(generative, "Answer about Khon Kaen University(KKU).You must always provide your answers in both Thai and English(Example. ภาษาไทย:สวัสดี English:Hello).(Must answer with raw text, do not include any HTML tags or formatting)"+prompt)
-			// if err != nil {
-			// 	return nil, err
-			// }
-			resp, err := generateContentWithGoogleAPI("System Query:\n"+`Answer about Khon Kaen University(KKU).
-			You must always provide your answers in both Thai and English
-			Must answer with raw text, do not include any HTML tags or formatting
-			For example:
-			User Query: Where are EN16101?
-			Your Response:
-			ภาษาไทย: อยู่ข้างตึก 50
-			English: Near 50th anniversary building
-			Must answer with raw text, do not include any HTML tags or formatting`+"\n\nUser Query:\n"+prompt, apiKey)
-			if err != nil {
-				fmt.Printf("Error generating content: %v\n", err)
+		if resp.Outcome == entity.OutcomeUnknown {
+			if resp, err = a.escalate(ctx, question, data, chunks, t, resp); err != nil {
 				return nil, err
 			}
-			// Assuming resp is of type map[string]interface{}
-			candidates, ok := resp["candidates"].([]interface{})
-			if !ok || len(candidates) == 0 {
-				fmt.Println(err)
-				return nil, fmt.Errorf("no candidates found")
+			if err := send(resp.Answer); err != nil {
+				return nil, err
 			}
+		}
+		resp.Attempts = t.attempts
+		return resp, nil
+	}
+}
 
-			candidate, ok := candidates[0].(map[string]interface{})
-			if !ok {
+// escalate follows up an unknown answer with the steps of
+// ai.unknown.escalation in order. A step that fails is skipped; when none
+// gives an answer the reply says the information is unavailable.
+func (a *aiRepository) escalate(ctx context.Context, question *entity.AiRequest, data prompt.Data, chunks []retrieval.Chunk, t *tries, unknown *entity.AiAnswer) (*entity.AiAnswer, error) {
+	// other providers are asked without streaming
+	data.JSON = true
+	for _, step := range a.escalation {
+		switch step {
+		case entity.EscalationSearch:
+			answer := &entity.AiAnswer{Outcome: entity.OutcomeAnswered, Escalation: step}
+			if err := a.groundedAnswer(ctx, question, answer, t); err != nil {
 				fmt.Println(err)
-				return nil, fmt.Errorf("candidate type assertion failed")
+				continue
 			}
-
-			content, ok := candidate["content"].(map[string]interface{})
-			if !ok {
-				fmt.Println(err)
-				return nil, fmt.Errorf("content type assertion failed")
+			return answer, nil
+		case entity.EscalationProvider:
+			if answer := a.askOtherProviders(ctx, question, data, chunks, t, unknown.Provider); answer != nil {
+				answer.Escalation = step
+				return answer, nil
 			}
-
-			parts, ok := content["parts"].([]interface{})
-			if !ok || len(parts) == 0 {
+		case entity.EscalationHandoff:
+			answer := *unknown
+			if err := a.notice(prompt.Handoff, &answer, question.Lang); err != nil {
 				fmt.Println(err)
-				return nil, fmt.Errorf("no parts found in content")
-			}
-			var textArr []string
-			for _, part := range parts {
-				firstPart, ok := part.(map[string]interface{})
-				if !ok {
-					fmt.Println(err)
-					return nil, fmt.Errorf("part type assertion failed")
-				}
-				text, ok := firstPart["text"].(string)
-				if !ok {
-					fmt.Println(err)
-					return nil, fmt.Errorf("text type assertion failed")
-				}
-				textArr = append(textArr, text)
+				continue
 			}
-			text := strings.Join(textArr, "\n")
-			aiAnswer.Answer = text
+			answer.Source = entity.SourceHandoff
+			answer.Escalation = step
+			return &answer, nil
+		default:
+			fmt.Printf("Unknown escalation %q\n", step)
+		}
+	}
+	answer := *unknown
+	if err := a.notice(prompt.Unknown, &answer, question.Lang); err != nil {
+		return nil, err
+	}
+	return &answer, nil
+}
 
+// askOtherProviders leaves out the provider ai, and every further provider
+// that does not know either, until one answers or refuses. It returns nil
+// when none is left.
+func (a *aiRepository) askOtherProviders(ctx context.Context, question *entity.AiRequest, data prompt.Data, chunks []retrieval.Chunk, t *tries, ai string) *entity.AiAnswer {
+	for {
+		for _, m := range a.ais[ai].Models {
+			t.failed[modelKey(ai, m)] = true
+		}
+		resp, err := a.generateWith(ctx, question, data, chunks, t)
+		if err != nil {
+			fmt.Println(err)
+			return nil
 		}
-		return aiAnswer, nil
+		if resp.Outcome != entity.OutcomeUnknown {
+			return resp
+		}
+		ai = resp.Provider
 	}
-	return nil, fmt.Errorf("unsupported AI model")
 }
-func dontKnow(generative *genai.GenerativeModel, prompt string) (*genai.GenerateContentResponse, error) {
-	resp, err := generative.GenerateContent(context.Background(), genai.Text(prompt))
-	if err != nil {
+
+// blocked answers a question the safety filter of the provider blocked with
+// the refused notice. It is not asked elsewhere, see retry.DefaultActions.
+func (a *aiRepository) blocked(ai string, model string, lang string) (*entity.AiAnswer, error) {
+	answer := &entity.AiAnswer{
+		Provider: ai,
+		Model:    model,
+		Source:   entity.SourceModel,
+		Outcome:  entity.OutcomeRefused,
+	}
+	if err := a.notice(prompt.Refused, answer, lang); err != nil {
 		return nil, err
 	}
-	return resp, nil
+	return answer, nil
 }
-func generateContentWithGoogleAPI(prompt string, apiKey string) (map[string]interface{}, error) {
-	// Prepare the JSON payload.
-	payload := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]string{
-					{"text": prompt},
-				},
-			},
-		},
-		"tools": []map[string]interface{}{
-			{
-				"google_search": map[string]interface{}{},
-			},
-		},
-	}
 
-	jsonData, err := json.Marshal(payload)
+// notice fills aiAnswer with the fixed text of the prompt name, e.g. the
+// handoff notice.
+func (a *aiRepository) notice(name string, aiAnswer *entity.AiAnswer, lang string) error {
+	text, version, err := a.prompts.Render(name, "", prompt.Data{Lang: lang})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal JSON payload: %w", err)
+		return err
 	}
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent?key=%s", apiKey)
+	setAnswer(aiAnswer, bilingual.Parse(text), lang)
+	aiAnswer.Citations = nil
+	aiAnswer.PromptVersion = version
+	return nil
+}
 
-	// Create the HTTP POST request.
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+// generate asks one model, waiting at most Timeouts.Attempt.
+func (a *aiRepository) generate(ctx context.Context, p provider.Provider, question *entity.AiRequest, system string, apiKey string, model string) (*entity.AiAnswer, error) {
+	ctx, cancel := a.timeouts.attempt(ctx)
+	defer cancel()
+	req := newRequest(question, system, apiKey, model)
+	req.JSON = true
+	a.keys.Use(p.Name(), apiKey)
+	resp, err := p.Generate(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, err
+	}
+	outcome, answer := parseReply(resp.Text)
+	aiAnswer := &entity.AiAnswer{
+		Provider:     p.Name(),
+		Model:        resp.Model,
+		FinishReason: resp.FinishReason,
+		InputTokens:  resp.InputTokens,
+		OutputTokens: resp.OutputTokens,
+		Source:       entity.SourceModel,
+		Outcome:      outcome,
 	}
-	req.Header.Set("Content-Type", "application/json")
+	setAnswer(aiAnswer, answer, question.Lang)
+	return aiAnswer, nil
+}
 
-	// Send the request.
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// stream works like generate but hands every chunk to send as soon as the
+// provider produces it. Output is held back while it could still be an
+// outcome marker; the marker of a refusal is left out and nothing of an
+// unknown answer is sent, so it can be escalated. Chunks are sent in the
+// languages the model writes, the system prompt asks it for question.Lang.
+// The first chunk must come within Timeouts.Attempt.
+func (a *aiRepository) stream(ctx context.Context, p provider.Provider, question *entity.AiRequest, system string, apiKey string, model string, send provider.StreamFunc) (*entity.AiAnswer, error) {
+	ctx, arrived, cancel := a.timeouts.firstChunk(ctx)
+	defer cancel()
+	req := newRequest(question, system, apiKey, model)
+	var reply strings.Builder
+	outcome := ""
+	a.keys.Use(p.Name(), apiKey)
+	resp, err := p.Stream(ctx, req, func(chunk string) error {
+		arrived()
+		reply.WriteString(chunk)
+		if outcome == "" {
+			if outcome, chunk, _ = textOutcome(reply.String()); outcome == "" {
+				return nil
+			}
+		}
+		if outcome == entity.OutcomeUnknown || chunk == "" {
+			return nil
+		}
+		return send(chunk)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return nil, firstChunkErr(ctx, err)
+	}
+	held := outcome == ""
+	outcome, rest, decided := textOutcome(resp.Text)
+	if !decided {
+		outcome = entity.OutcomeAnswered
 	}
-	defer resp.Body.Close()
+	aiAnswer := &entity.AiAnswer{
+		Provider:     p.Name(),
+		Model:        resp.Model,
+		FinishReason: resp.FinishReason,
+		InputTokens:  resp.InputTokens,
+		OutputTokens: resp.OutputTokens,
+		Source:       entity.SourceModel,
+		Outcome:      outcome,
+	}
+	// the chunks sent are not filtered by language, the answer is
+	setAnswer(aiAnswer, bilingual.Parse(rest), question.Lang)
+	if held && outcome != entity.OutcomeUnknown && rest != "" {
+		if err := send(rest); err != nil {
+			return nil, err
+		}
+	}
+	return aiAnswer, nil
+}
 
-	// Check if the response status code indicates success.
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("non-200 response: %d, body: %s", resp.StatusCode, string(bodyBytes))
+// setAnswer fills the answer in the languages asked for.
+func setAnswer(aiAnswer *entity.AiAnswer, answer bilingual.Answer, lang string) {
+	answer = answer.In(lang)
+	aiAnswer.Answer = answer.String()
+	aiAnswer.AnswerTH = answer.TH
+	aiAnswer.AnswerEN = answer.EN
+}
+
+func newRequest(question *entity.AiRequest, system string, apiKey string, model string) *provider.Request {
+	return &provider.Request{
+		Model:         model,
+		APIKey:        apiKey,
+		System:        system,
+		Prompt:        question.Question,
+		History:       toMessages(question.History),
+		Image:         question.Image,
+		ImageMimeType: question.ImageMimeType,
 	}
+}
 
-	// Read and return the response body.
-	result, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+// searchKnowledge returns the knowledge base entries relevant to question.
+// The last earlier question is searched too, so follow ups like "and for
+// the second semester?" still find the entries of the topic.
+func (a *aiRepository) searchKnowledge(ctx context.Context, question *entity.AiRequest) (string, []retrieval.Chunk, error) {
+	query := question.Question
+	for i := len(question.History) - 1; i >= 0; i-- {
+		if question.History[i].Role == "user" {
+			query = question.History[i].Content + "\n" + query
+			break
+		}
 	}
-	// convert to *genai.GenerateContentResponse
-	var response map[string]interface{}
-	err = json.Unmarshal(result, &response)
+	results, err := a.knowledge.Search(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+		fmt.Println(err)
+		return "", nil, fmt.Errorf("failed to search knowledge: %w", err)
+	}
+	var knowledge strings.Builder
+	chunks := make([]retrieval.Chunk, 0, len(results))
+	for _, r := range results {
+		fmt.Fprintf(&knowledge, "[%s]\n%s\n\n", r.Chunk.Citation(), r.Chunk.Text)
+		chunks = append(chunks, r.Chunk)
 	}
+	return knowledge.String(), chunks, nil
+}
 
-	return response, nil
+// groundedAnswer replaces the answer by asking Gemini again with Google
+// Search grounding enabled, citing the pages it was found on. It needs a
+// Gemini key even when the first answer came from another provider. Calls
+// are recorded in t, and failures acted on as the retry policy says, like
+// the calls of the chain.
+func (a *aiRepository) groundedAnswer(ctx context.Context, question *entity.AiRequest, aiAnswer *entity.AiAnswer, t *tries) error {
+	model := a.grounding.Model()
+	text, version, err := a.prompts.Render(prompt.Grounded, "gemini", prompt.Data{Lang: question.Lang, Question: question.Question})
+	if err != nil {
+		return err
+	}
+	var apiKey string
+	again := false
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !again {
+			var ok bool
+			apiKey, ok = a.keys.Pick("gemini", func(k string) bool {
+				return !t.failed[keyKey("gemini", k)] && a.health.KeyAvailable(ctx, "gemini", k)
+			})
+			if !ok || t.failed[modelKey("gemini", model)] || !a.health.ModelAvailable(ctx, "gemini", model) {
+				return fmt.Errorf("grounded search needs a gemini key and model that have not failed")
+			}
+		}
+		start := time.Now()
+		a.keys.Use("gemini", apiKey)
+		resp, err := a.grounding.Search(ctx, apiKey, text)
+		if err != nil {
+			if again, err = a.failed(ctx, t, "gemini", model, apiKey, start, err); err != nil {
+				return err
+			}
+			continue
+		}
+		setAnswer(aiAnswer, bilingual.Parse(resp.Text()), question.Lang)
+		aiAnswer.Source = entity.SourceSearch
+		aiAnswer.Citations = webCitations(resp.Sources())
+		aiAnswer.PromptVersion = version
+		aiAnswer.Provider = "gemini"
+		aiAnswer.Model = resp.ModelVersion
+		if aiAnswer.Model == "" {
+			aiAnswer.Model = model
+		}
+		aiAnswer.FinishReason = resp.Candidates[0].FinishReason
+		if resp.UsageMetadata != nil {
+			aiAnswer.InputTokens, aiAnswer.OutputTokens = resp.UsageMetadata.PromptTokenCount, resp.UsageMetadata.CandidatesTokenCount
+		}
+		a.succeeded(t, "gemini", model, apiKey, start, aiAnswer)
+		return nil
+	}
 }
 func ableToRead(text string) []string {
 	var answer []string
@@ -263,12 +527,159 @@ func ableToRead(text string) []string {
 	}
 	return answer
 }
-func (a *aiRepository) Ask(req *pb.AiRequest) (*pb.AiResponse, error) {
-	resp, err := generateContentWithFallback(context.Background(), req.Question, nil, "gemini", a.GeminiKey.Keys, a.GeminiKey.Models)
+
+// toEntity converts req and trims its history to the token budget.
+func (a *aiRepository) toEntity(req *pb.AiRequest) *entity.AiRequest {
+	history := make([]entity.Message, 0, len(req.History))
+	for _, m := range req.History {
+		history = append(history, entity.Message{Role: m.Role, Content: m.Content})
+	}
+	lang := req.Lang
+	if lang != knowledge.Thai && lang != knowledge.English {
+		lang = ""
+	}
+	return &entity.AiRequest{
+		Question:      req.Question,
+		Image:         req.Image,
+		ImageMimeType: req.ImageMimeType,
+		History:       trimHistory(history, a.historyBudget),
+		Lang:          lang,
+	}
+}
+
+// matchFAQ returns the curated answer when the question is close enough to
+// a FAQ question, so no model is called. Questions with a photo or earlier
+// turns are always left to the model: a follow up like "and for the second
+// semester?" can look like a FAQ question it is not. Errors only cost the
+// shortcut.
+func (a *aiRepository) matchFAQ(ctx context.Context, question *entity.AiRequest) *entity.AiAnswer {
+	if len(question.Image) > 0 || len(question.History) > 0 {
+		return nil
+	}
+	match, err := a.knowledge.Match(ctx, question.Question)
 	if err != nil {
-		return nil, err
+		fmt.Println(err)
+		return nil
+	}
+	if match == nil {
+		return nil
 	}
+	answer := &entity.AiAnswer{
+		Source:  entity.SourceFAQ,
+		Outcome: entity.OutcomeAnswered,
+		Citations: []entity.Citation{
+			{Source: match.Chunk.Source, EntryID: match.Chunk.ID, Title: match.Chunk.Title},
+		},
+	}
+	faq := bilingual.Answer{TH: match.Chunk.Answers[knowledge.Thai], EN: match.Chunk.Answers[knowledge.English]}
+	if faq.TH == "" && faq.EN == "" {
+		faq = bilingual.Parse(match.Chunk.Answer)
+	}
+	setAnswer(answer, faq, question.Lang)
+	return answer
+}
+
+func (a *aiRepository) Ask(ctx context.Context, req *pb.AiRequest) (*pb.AiResponse, error) {
+	ctx, cancel := a.timeouts.budget(ctx)
+	defer cancel()
+	question := a.toEntity(req)
+	resp := a.matchFAQ(ctx, question)
+	if resp == nil {
+		var err error
+		resp, err = a.generateContentWithFallback(ctx, question)
+		if err != nil {
+			return nil, err
+		}
+	}
+	resp.KnowledgeVersion = a.knowledgeVersion(ctx)
 	return &pb.AiResponse{
-		Answer: resp.Answer,
+		Answer:           resp.Answer,
+		Source:           resp.Source,
+		KnowledgeVersion: resp.KnowledgeVersion,
+		Citations:        toPbCitations(resp.Citations),
+		AnswerTh:         resp.AnswerTH,
+		AnswerEn:         resp.AnswerEN,
+		PromptVersion:    resp.PromptVersion,
+		Outcome:          resp.Outcome,
+		Escalation:       resp.Escalation,
+		Attempts:         toPbAttempts(req.Debug, resp.Attempts),
 	}, nil
 }
+func (a *aiRepository) AskStream(ctx context.Context, req *pb.AiRequest, send func(*pb.AiStreamResponse) error) error {
+	ctx, cancel := a.timeouts.budget(ctx)
+	defer cancel()
+	question := a.toEntity(req)
+	resp := a.matchFAQ(ctx, question)
+	if resp != nil {
+		if err := send(&pb.AiStreamResponse{Chunk: resp.Answer}); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		resp, err = a.streamContentWithFallback(ctx, question, func(chunk string) error {
+			return send(&pb.AiStreamResponse{Chunk: chunk})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	resp.KnowledgeVersion = a.knowledgeVersion(ctx)
+	return send(&pb.AiStreamResponse{
+		Done:             true,
+		Model:            resp.Model,
+		FinishReason:     resp.FinishReason,
+		Source:           resp.Source,
+		KnowledgeVersion: resp.KnowledgeVersion,
+		Citations:        toPbCitations(resp.Citations),
+		AnswerTh:         resp.AnswerTH,
+		AnswerEn:         resp.AnswerEN,
+		PromptVersion:    resp.PromptVersion,
+		Outcome:          resp.Outcome,
+		Escalation:       resp.Escalation,
+		Attempts:         toPbAttempts(req.Debug, resp.Attempts),
+	})
+}
+
+// knowledgeVersion is recorded with every answer. The answer was already
+// given when it is asked for, so a failure only leaves it empty.
+func (a *aiRepository) knowledgeVersion(ctx context.Context) string {
+	version, err := a.knowledge.Version(ctx)
+	if err != nil {
+		fmt.Println(err)
+	}
+	return version
+}
+
+func (a *aiRepository) GetHealth(ctx context.Context) (*pb.HealthResponse, error) {
+	cooldowns, err := a.health.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.HealthResponse{}
+	for _, k := range a.keys.Stats() {
+		res.Keys = append(res.Keys, &pb.KeyStats{
+			Provider:            k.Provider,
+			Key:                 k.Key,
+			Requests:            k.Requests,
+			Failures:            k.Failures,
+			QuotaErrors:         k.QuotaErrors,
+			ConsecutiveFailures: k.ConsecutiveFailures,
+			InputTokens:         k.InputTokens,
+			OutputTokens:        k.OutputTokens,
+			LatencyMs:           k.Latency.Milliseconds(),
+			UsedToday:           k.UsedToday,
+			DailyQuota:          k.DailyQuota,
+			Weight:              k.Weight,
+		})
+	}
+	for _, c := range cooldowns {
+		res.Cooldowns = append(res.Cooldowns, &pb.Cooldown{
+			Provider: c.Provider,
+			Model:    c.Model,
+			Key:      c.Key,
+			Until:    c.Until.Unix(),
+			Reason:   c.Reason,
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"ai/internal/entity"
+	"ai/internal/health"
+	"ai/internal/keypool"
+	"ai/internal/prompt"
+	"ai/internal/provider"
+	"ai/internal/retry"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// visionProvider is a fakeProvider that also reads images.
+type visionProvider struct {
+	*fakeProvider
+}
+
+func (v visionProvider) Modalities() []provider.Modality {
+	return []provider.Modality{provider.Text, provider.Image}
+}
+
+func newChainRepository(t *testing.T, chain []string, providers ...provider.Provider) *aiRepository {
+	registry := provider.NewRegistry()
+	ais := map[string]entity.Ai{}
+	keys := keypool.New(keypool.RoundRobin)
+	for _, p := range providers {
+		assert.NoError(t, registry.Register(p))
+		ais[p.Name()] = entity.Ai{Keys: []string{p.Name() + "-key"}, Models: []string{p.Name() + "-model"}}
+		keys.Add(p.Name(), ais[p.Name()].Keys, 0)
+	}
+	prompts := prompt.NewSet(prompt.Config{Dir: "../../prompts"})
+	assert.NoError(t, prompts.Reload())
+	cooldowns := health.NewRegistry(health.NewMemoryStore(), health.Config{})
+	return NewAiRepository(registry, ais, chain, cooldowns, keys, 0, nil, prompts, nil, nil, Timeouts{}, retry.Policy{Retries: -1}).(*aiRepository)
+}
+
+func TestChooseModel(t *testing.T) {
+	ctx := context.Background()
+	gemini := visionProvider{&fakeProvider{name: "gemini"}}
+	deepseek := &fakeProvider{name: "deepseek"}
+
+	t.Run("registration order by default", func(t *testing.T) {
+		a := newChainRepository(t, nil, gemini, deepseek)
+		p, model, key := a.chooseModel(ctx, map[string]bool{}, false)
+		assert.Equal(t, "gemini", p.Name())
+		assert.Equal(t, "gemini-model", model)
+		assert.Equal(t, "gemini-key", key)
+	})
+	t.Run("configured order", func(t *testing.T) {
+		a := newChainRepository(t, []string{"claude", "deepseek", "gemini"}, gemini, deepseek)
+		p, _, _ := a.chooseModel(ctx, map[string]bool{}, false)
+		// claude is not registered and left out
+		assert.Equal(t, "deepseek", p.Name())
+	})
+	t.Run("images need a provider that reads them", func(t *testing.T) {
+		a := newChainRepository(t, []string{"deepseek", "gemini"}, gemini, deepseek)
+		p, _, _ := a.chooseModel(ctx, map[string]bool{}, true)
+		assert.Equal(t, "gemini", p.Name())
+	})
+	t.Run("failed and cooling down", func(t *testing.T) {
+		a := newChainRepository(t, []string{"deepseek", "gemini"}, gemini, deepseek)
+		p, _, _ := a.chooseModel(ctx, map[string]bool{modelKey("deepseek", "deepseek-model"): true}, false)
+		assert.Equal(t, "gemini", p.Name())
+
+		a.health.Report(ctx, "deepseek", "deepseek-model", "deepseek-key", statusError(http.StatusUnauthorized))
+		p, _, _ = a.chooseModel(ctx, map[string]bool{}, false)
+		assert.Equal(t, "gemini", p.Name())
+
+		a.health.Report(ctx, "gemini", "gemini-model", "gemini-key", statusError(http.StatusServiceUnavailable))
+		p, _, _ = a.chooseModel(ctx, map[string]bool{}, false)
+		assert.Nil(t, p)
+	})
+}
+
+func TestFallbackAcrossProviders(t *testing.T) {
+	ctx := context.Background()
+	gemini := &fakeProvider{name: "gemini", errors: map[string][]error{"gemini-model": {statusError(http.StatusInternalServerError)}}}
+	deepseek := &fakeProvider{name: "deepseek"}
+	a := newChainRepository(t, []string{"gemini", "deepseek"}, gemini, deepseek)
+
+	tries := newTries()
+	resp, err := a.generateWith(ctx, &entity.AiRequest{Question: "When does the office open?"}, prompt.Data{}, nil, tries)
+	assert.NoError(t, err)
+	assert.Equal(t, "deepseek", resp.Provider)
+	assert.Len(t, gemini.calls, 1)
+	assert.Len(t, deepseek.calls, 1)
+
+	// gemini is cooling down, the next question goes to deepseek right away
+	resp, err = a.generateWith(ctx, &entity.AiRequest{Question: "And on Saturday?"}, prompt.Data{}, nil, newTries())
+	assert.NoError(t, err)
+	assert.Equal(t, "deepseek", resp.Provider)
+	assert.Len(t, gemini.calls, 1)
+}
+
+func TestMatchFAQ(t *testing.T) {
+	ctx := context.Background()
+	a := newTestRepository(t, &fakeProvider{name: "gemini"}, retry.Policy{})
+	withKnowledge(t, a, map[string]string{"question.json": `{"data": [
+		{"_comment": "ลาพัก", "question": "การลาพักการศึกษาจะต้องดำเนินการอย่างไรบ้าง", "answer": "ยื่นคำร้องลาพักการศึกษา"}
+	]}`})
+	question := &entity.AiRequest{Question: "การลาพักการศึกษาจะต้องดำเนินการอย่างไรบ้าง"}
+
+	answer := a.matchFAQ(ctx, question)
+	if assert.NotNil(t, answer) {
+		assert.Equal(t, entity.SourceFAQ, answer.Source)
+		assert.Equal(t, "ยื่นคำร้องลาพักการศึกษา", answer.AnswerTH)
+	}
+	// a follow up is left to the model, which sees the earlier turns
+	question.History = []entity.Message{
+		{Role: "user", Content: "ลงทะเบียนเรียนล่าช้าได้ไหม"},
+		{Role: "assistant", Content: "ได้ โดยเสียค่าปรับ"},
+	}
+	assert.Nil(t, a.matchFAQ(ctx, question))
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"ai/internal/entity"
+	"ai/internal/provider"
+	"ai/internal/retrieval"
+	"ai/internal/retry"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// streamingProvider streams chunks, or fails with the errors of the model
+// in turn like fakeProvider. With cutOff it fails after the first chunk.
+type streamingProvider struct {
+	*fakeProvider
+	chunks []string
+	cutOff bool
+}
+
+func (s *streamingProvider) Stream(ctx context.Context, req *provider.Request, send provider.StreamFunc) (*provider.Response, error) {
+	if _, err := s.Generate(ctx, req); err != nil {
+		return nil, err
+	}
+	text := ""
+	for _, chunk := range s.chunks {
+		if err := send(chunk); err != nil {
+			return nil, err
+		}
+		text += chunk
+		if s.cutOff {
+			return nil, errors.New("stream reset")
+		}
+	}
+	return &provider.Response{Text: text, Model: req.Model, FinishReason: "stop"}, nil
+}
+
+// withKnowledge gives a the knowledge files, name to content.
+func withKnowledge(t *testing.T, a *aiRepository, files map[string]string) {
+	dir := t.TempDir()
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	a.knowledge = retrieval.NewRetriever(retrieval.Config{Dir: dir})
+	assert.NoError(t, a.knowledge.Reload(context.Background()))
+}
+
+func TestStreamContentWithFallback(t *testing.T) {
+	ctx := context.Background()
+	question := &entity.AiRequest{Question: "When does the office open?", Lang: "en"}
+	knowledge := map[string]string{"hours.md": "The office opens at 9 and closes at 16."}
+	collect := func(chunks *[]string) provider.StreamFunc {
+		return func(chunk string) error {
+			*chunks = append(*chunks, chunk)
+			return nil
+		}
+	}
+
+	t.Run("chunks as they come", func(t *testing.T) {
+		p := &streamingProvider{fakeProvider: &fakeProvider{name: "gemini"}, chunks: []string{"The office ", "opens at 9."}}
+		a := newTestRepository(t, p, retry.Policy{})
+		withKnowledge(t, a, knowledge)
+		var chunks []string
+		resp, err := a.streamContentWithFallback(ctx, question, collect(&chunks))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"The office ", "opens at 9."}, chunks)
+		assert.Equal(t, "The office opens at 9.", resp.Answer)
+		assert.Equal(t, "stop", resp.FinishReason)
+		assert.Equal(t, entity.OutcomeAnswered, resp.Outcome)
+		assert.Len(t, resp.Attempts, 1)
+	})
+	t.Run("fall back before anything is sent", func(t *testing.T) {
+		down := statusError(http.StatusServiceUnavailable)
+		p := &streamingProvider{fakeProvider: &fakeProvider{name: "gemini", errors: map[string][]error{"flash": {down}}}, chunks: []string{"opens at 9."}}
+		a := newTestRepository(t, p, retry.Policy{Retries: -1})
+		withKnowledge(t, a, knowledge)
+		var chunks []string
+		resp, err := a.streamContentWithFallback(ctx, question, collect(&chunks))
+		assert.NoError(t, err)
+		assert.Equal(t, "pro", resp.Model)
+		assert.Equal(t, []string{"opens at 9."}, chunks)
+		assert.Len(t, resp.Attempts, 2)
+	})
+	t.Run("no fallback once sent", func(t *testing.T) {
+		p := &streamingProvider{fakeProvider: &fakeProvider{name: "gemini"}, chunks: []string{"The office ", "opens at 9."}, cutOff: true}
+		a := newTestRepository(t, p, retry.Policy{})
+		withKnowledge(t, a, knowledge)
+		var chunks []string
+		_, err := a.streamContentWithFallback(ctx, question, collect(&chunks))
+		assert.ErrorContains(t, err, "stream reset")
+		assert.Equal(t, []string{"The office "}, chunks)
+		assert.Len(t, p.calls, 1)
+	})
+	t.Run("unknown answers are not sent", func(t *testing.T) {
+		p := &streamingProvider{fakeProvider: &fakeProvider{name: "gemini"}, chunks: []string{"[UNK", "NOWN] no idea"}}
+		a := newTestRepository(t, p, retry.Policy{})
+		withKnowledge(t, a, knowledge)
+		var chunks []string
+		resp, err := a.streamContentWithFallback(ctx, question, collect(&chunks))
+		assert.NoError(t, err)
+		assert.Equal(t, entity.OutcomeUnknown, resp.Outcome)
+		// only the notice that the information is unavailable
+		assert.Len(t, chunks, 1)
+		assert.NotContains(t, chunks[0], "no idea")
+	})
+	t.Run("answer in the language asked for", func(t *testing.T) {
+		p := &streamingProvider{fakeProvider: &fakeProvider{name: "gemini"}, chunks: []string{"ภาษาไทย: เปิด 9 โมง\n", "English: It opens at 9."}}
+		a := newTestRepository(t, p, retry.Policy{})
+		withKnowledge(t, a, knowledge)
+		var chunks []string
+		resp, err := a.streamContentWithFallback(ctx, question, collect(&chunks))
+		assert.NoError(t, err)
+		// the stream is the model's text, the answer is English only
+		assert.Len(t, chunks, 2)
+		assert.Equal(t, "It opens at 9.", resp.Answer)
+		assert.Equal(t, "It opens at 9.", resp.AnswerEN)
+		assert.Empty(t, resp.AnswerTH)
+	})
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errNoFirstChunk is the cause of a stream cut off by firstChunk.
+var errNoFirstChunk = fmt.Errorf("no first chunk in time: %w", context.DeadlineExceeded)
+
+// Timeouts bound the time spent on one question.
+type Timeouts struct {
+	// Attempt bounds one call to a model, or the wait for the first chunk
+	// of a stream; 30s by default. A model that takes longer is reported
+	// like any other failure and the next one is tried.
+	Attempt time.Duration
+	// Total bounds the whole question with its fallbacks and escalation;
+	// 55s by default.
+	Total time.Duration
+	// Reserve is kept back from the deadline of the caller, so an answer
+	// found at the last moment still reaches it; 1s by default.
+	Reserve time.Duration
+}
+
+func (t Timeouts) withDefaults() Timeouts {
+	if t.Attempt <= 0 {
+		t.Attempt = 30 * time.Second
+	}
+	if t.Total <= 0 {
+		t.Total = 55 * time.Second
+	}
+	if t.Reserve <= 0 {
+		t.Reserve = time.Second
+	}
+	return t
+}
+
+// budget bounds ctx by Total, or by the deadline of ctx less Reserve when
+// that comes first.
+func (t Timeouts) budget(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline := time.Now().Add(t.Total)
+	if d, ok := ctx.Deadline(); ok && d.Add(-t.Reserve).Before(deadline) {
+		deadline = d.Add(-t.Reserve)
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// attempt bounds one call to a model within the budget of ctx.
+func (t Timeouts) attempt(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, t.Attempt)
+}
+
+// firstChunk bounds a stream until stop is called, which the caller does
+// once the first chunk arrives; after that only ctx bounds it. See
+// firstChunkErr for the error of a stream cut off this way.
+func (t Timeouts) firstChunk(ctx context.Context) (attemptCtx context.Context, stop func(), cancel context.CancelFunc) {
+	attemptCtx, cancelAttempt := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(t.Attempt, func() { cancelAttempt(errNoFirstChunk) })
+	return attemptCtx, func() { timer.Stop() }, func() {
+		timer.Stop()
+		cancelAttempt(context.Canceled)
+	}
+}
+
+// firstChunkErr returns the error of a stream that failed with err. When
+// firstChunk cut it off, the provider only saw a cancelled context; the
+// error then wraps context.DeadlineExceeded instead, like a call that ran
+// past attempt, so it is retried as a transient error.
+func firstChunkErr(attemptCtx context.Context, err error) error {
+	if cause := context.Cause(attemptCtx); errors.Is(cause, errNoFirstChunk) {
+		return fmt.Errorf("%w: %v", cause, err)
+	}
+	return err
+}
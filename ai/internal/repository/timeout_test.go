@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"ai/internal/retry"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeouts(t *testing.T) {
+	timeouts := Timeouts{Attempt: 50 * time.Millisecond, Total: time.Minute, Reserve: time.Second}.withDefaults()
+
+	t.Run("budget without a deadline", func(t *testing.T) {
+		ctx, cancel := timeouts.budget(context.Background())
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+	})
+	t.Run("budget within the caller's deadline", func(t *testing.T) {
+		parent, cancelParent := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelParent()
+		ctx, cancel := timeouts.budget(parent)
+		defer cancel()
+		deadline, _ := ctx.Deadline()
+		parentDeadline, _ := parent.Deadline()
+		assert.Equal(t, parentDeadline.Add(-time.Second), deadline)
+	})
+	t.Run("no budget left", func(t *testing.T) {
+		parent, cancelParent := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancelParent()
+		ctx, cancel := timeouts.budget(parent)
+		defer cancel()
+		assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	})
+	t.Run("first chunk", func(t *testing.T) {
+		ctx, arrived, cancel := timeouts.firstChunk(context.Background())
+		defer cancel()
+		arrived()
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, ctx.Err())
+
+		ctx, _, cancel = timeouts.firstChunk(context.Background())
+		defer cancel()
+		<-ctx.Done()
+		err := firstChunkErr(ctx, fmt.Errorf("read stream: %w", ctx.Err()))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, retry.Transient, retry.Classify(err))
+	})
+	t.Run("stream failing on its own", func(t *testing.T) {
+		ctx, _, cancel := timeouts.firstChunk(context.Background())
+		defer cancel()
+		err := errors.New("connection closed")
+		assert.Equal(t, err, firstChunkErr(ctx, err))
+		cancel()
+		assert.ErrorIs(t, firstChunkErr(ctx, ctx.Err()), context.Canceled)
+		assert.NotErrorIs(t, firstChunkErr(ctx, ctx.Err()), context.DeadlineExceeded)
+	})
+	t.Run("defaults", func(t *testing.T) {
+		assert.Equal(t, Timeouts{Attempt: 30 * time.Second, Total: 55 * time.Second, Reserve: time.Second}, Timeouts{}.withDefaults())
+	})
+}
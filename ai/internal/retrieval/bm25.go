@@ -0,0 +1,166 @@
+package retrieval
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// k1 and b are the usual BM25 parameters: term frequency saturation and
+	// document length normalisation.
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Chunk is one piece of a knowledge file, e.g. one FAQ entry.
+type Chunk struct {
+	// Source is the file the chunk was read from, relative to the knowledge dir.
+	Source string
+	Title  string
+	Text   string
+	// Answer is the curated answer of a FAQ entry, empty for other chunks.
+	Answer string
+	// Answers is the curated answer in every language of the entry.
+	Answers map[string]string
+	// ID, Version and Language identify the knowledge base entry a chunk was
+	// made from, they are empty for plain files.
+	ID       string
+	Version  int
+	Language string
+	// Page is the 1-based page of a PDF chunk, 0 for other files.
+	Page int
+	// From and Until bound when the chunk may be used, Until is exclusive.
+	// Zero means unbounded.
+	From  time.Time
+	Until time.Time
+}
+
+// key identifies a chunk across indexes, e.g. a chunk read back from a vector
+// store, whose times compare unequal to the original.
+func (c Chunk) key() string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%d\x00%s\x00%s", c.Source, c.ID, c.Language, c.Page, c.Title, c.Text)
+}
+
+// EffectiveAt reports whether the chunk may be used at t.
+func (c Chunk) EffectiveAt(t time.Time) bool {
+	return (c.From.IsZero() || !t.Before(c.From)) && (c.Until.IsZero() || t.Before(c.Until))
+}
+
+type Result struct {
+	Chunk Chunk
+	Score float64
+}
+
+// Index is an in-memory BM25 index. It is never changed after NewIndex, so it
+// can be searched concurrently.
+type Index struct {
+	chunks []Chunk
+	terms  []map[string]int
+	// questions holds the terms of the FAQ questions, see Match.
+	questions []map[string]int
+	lengths   []int
+	avgLen    float64
+	// df is the number of chunks each term appears in.
+	df      map[string]int
+	version string
+}
+
+func NewIndex(chunks []Chunk) *Index {
+	x := &Index{
+		chunks:    chunks,
+		terms:     make([]map[string]int, len(chunks)),
+		questions: make([]map[string]int, len(chunks)),
+		lengths:   make([]int, len(chunks)),
+		df:        map[string]int{},
+	}
+	total := 0
+	for i, c := range chunks {
+		tf := map[string]int{}
+		terms := Segment(c.Title + "\n" + c.Text)
+		for _, t := range terms {
+			tf[t]++
+		}
+		for t := range tf {
+			x.df[t]++
+		}
+		x.terms[i] = tf
+		if c.Answer != "" {
+			x.questions[i] = termCounts(c.Title)
+		}
+		x.lengths[i] = len(terms)
+		total += len(terms)
+	}
+	if len(chunks) > 0 {
+		x.avgLen = float64(total) / float64(len(chunks))
+	}
+	x.version = versionOf(chunks)
+	return x
+}
+
+func (x *Index) Len() int {
+	return len(x.chunks)
+}
+
+// Version names the knowledge base versions in the index, e.g.
+// "question.json@3", sorted and comma separated. Plain files have none.
+func (x *Index) Version() string {
+	return x.version
+}
+
+func versionOf(chunks []Chunk) string {
+	seen := map[string]bool{}
+	var versions []string
+	for _, c := range chunks {
+		v := fmt.Sprintf("%s@%d", c.Source, c.Version)
+		if c.Version > 0 && !seen[v] {
+			seen[v] = true
+			versions = append(versions, v)
+		}
+	}
+	sort.Strings(versions)
+	return strings.Join(versions, ",")
+}
+
+// Search returns up to k chunks matching query, best first. Chunks sharing no
+// term with query are left out.
+func (x *Index) Search(query string, k int) []Result {
+	seen := map[string]bool{}
+	var terms []string
+	for _, t := range Segment(query) {
+		if !seen[t] && x.df[t] > 0 {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	var results []Result
+	n := float64(len(x.chunks))
+	now := time.Now()
+	for i, tf := range x.terms {
+		if !x.chunks[i].EffectiveAt(now) {
+			continue
+		}
+		score := 0.0
+		for _, t := range terms {
+			f := float64(tf[t])
+			if f == 0 {
+				continue
+			}
+			df := float64(x.df[t])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * f * (k1 + 1) / (f + k1*(1-b+b*float64(x.lengths[i])/x.avgLen))
+		}
+		if score > 0 {
+			results = append(results, Result{x.chunks[i], score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
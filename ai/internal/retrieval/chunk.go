@@ -0,0 +1,215 @@
+package retrieval
+
+import (
+	"ai/internal/knowledge"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxChunkLen is the number of characters plain text chunks are cut at.
+const maxChunkLen = 1000
+
+// ignored files live next to the knowledge but are not part of it.
+var ignored = map[string]bool{
+	"env.txt": true,
+}
+
+// indexable reports whether name is a knowledge file the index reads.
+func indexable(name string) bool {
+	if ignored[name] {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".txt", ".md", ".pdf", ".docx":
+		return true
+	}
+	return false
+}
+
+// LoadDir reads and chunks every knowledge file in dir, by file name. A
+// file that cannot be read or chunked is skipped with a log line rather than
+// holding back the rest; it keeps its chunks in previous, the result of the
+// last load, if it had any.
+func LoadDir(dir string, previous map[string][]Chunk) (map[string][]Chunk, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]Chunk{}
+	for _, e := range entries {
+		if e.IsDir() || !indexable(e.Name()) {
+			continue
+		}
+		c, err := loadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			if last, ok := previous[e.Name()]; ok {
+				log.Printf("Keeping the last good version of %s: %v", e.Name(), err)
+				files[e.Name()] = last
+			} else {
+				log.Printf("Skipping %s: %v", e.Name(), err)
+			}
+			continue
+		}
+		files[e.Name()] = c
+	}
+	return files, nil
+}
+
+func loadFile(path string) ([]Chunk, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return chunkFile(filepath.Base(path), data)
+}
+
+// flatten returns the chunks of files in file name order.
+func flatten(files map[string][]Chunk) []Chunk {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var chunks []Chunk
+	for _, name := range names {
+		chunks = append(chunks, files[name]...)
+	}
+	return chunks
+}
+
+func chunkFile(name string, data []byte) ([]Chunk, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return chunkJSON(name, data)
+	case ".pdf":
+		if len(data) == 0 {
+			return nil, fmt.Errorf("empty file")
+		}
+		return chunkPDF(name, data)
+	case ".docx":
+		return chunkDocx(name, data)
+	}
+	return chunkText(name, string(data)), nil
+}
+
+// chunkJSON makes a chunk of every entry of a knowledge base, one per
+// language it is written in. Entries are skipped outside their effective
+// dates by Index, and dropped with a log line when they are invalid. JSON
+// that is not a knowledge base is chunked per element.
+func chunkJSON(name string, data []byte) ([]Chunk, error) {
+	base, problems, err := knowledge.Load(data)
+	if errors.Is(err, knowledge.ErrUnknownFormat) {
+		return chunkRawJSON(name, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, problem := range problems {
+		log.Printf("Dropping from %s: %v", name, problem)
+	}
+	var chunks []Chunk
+	for _, c := range base.Categories {
+		for _, e := range c.Entries {
+			for _, lang := range knowledge.Languages {
+				question, ok := e.Question[lang]
+				if !ok {
+					continue
+				}
+				chunk := Chunk{
+					Source:   name,
+					ID:       e.ID,
+					Version:  base.Version,
+					Language: lang,
+					Title:    question,
+					Text:     entryText(c, e, lang),
+					Answer:   e.Answer[lang],
+					Answers:  e.Answer,
+				}
+				if e.EffectiveFrom != nil {
+					chunk.From = e.EffectiveFrom.Time
+				}
+				if e.EffectiveUntil != nil {
+					chunk.Until = e.EffectiveUntil.AddDate(0, 0, 1)
+				}
+				chunks = append(chunks, chunk)
+			}
+		}
+	}
+	return chunks, nil
+}
+
+func entryText(c knowledge.Category, e knowledge.Entry, lang string) string {
+	var text strings.Builder
+	if name := c.Name[lang]; name != "" {
+		text.WriteString(name + "\n")
+	} else if name := c.Name[knowledge.Thai]; name != "" {
+		text.WriteString(name + "\n")
+	}
+	text.WriteString("Q: " + e.Question[lang] + "\nA: " + e.Answer[lang])
+	if len(e.Tags) > 0 {
+		text.WriteString("\nTags: " + strings.Join(e.Tags, ", "))
+	}
+	if len(e.Sources) > 0 {
+		text.WriteString("\nSources: " + strings.Join(e.Sources, " "))
+	}
+	return text.String()
+}
+
+// chunkRawJSON keeps every element of an object's "data" or of a bare array
+// as compact JSON, or the whole document when it is neither.
+func chunkRawJSON(name string, data []byte) ([]Chunk, error) {
+	var entries []json.RawMessage
+	var wrapped struct {
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Data != nil {
+		entries = wrapped.Data
+	} else if err := json.Unmarshal(data, &entries); err != nil {
+		entries = []json.RawMessage{data}
+	}
+	chunks := make([]Chunk, 0, len(entries))
+	for _, raw := range entries {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, raw); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, Chunk{Source: name, Text: compact.String()})
+	}
+	return chunks, nil
+}
+
+// chunkText cuts plain text at blank lines and packs paragraphs together up
+// to maxChunkLen characters.
+func chunkText(name string, text string) []Chunk {
+	var chunks []Chunk
+	var current strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, Chunk{Source: name, Text: s})
+		}
+		current.Reset()
+	}
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		for _, part := range splitLong(p) {
+			if current.Len() > 0 && utf8.RuneCountInString(current.String())+utf8.RuneCountInString(part) > maxChunkLen {
+				flush()
+			}
+			current.WriteString(part)
+			current.WriteString("\n\n")
+		}
+	}
+	flush()
+	return chunks
+}
@@ -0,0 +1,62 @@
+package retrieval
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// docxBody is the part of a Word document that holds its text.
+const docxBody = "word/document.xml"
+
+// chunkDocx extracts the paragraphs of a Word document. Headers, footers
+// and comments are left out.
+func chunkDocx(name string, data []byte) ([]Chunk, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	f, err := r.Open(docxBody)
+	if err != nil {
+		return nil, fmt.Errorf("not a Word document: %w", err)
+	}
+	defer f.Close()
+	var text strings.Builder
+	decoder := xml.NewDecoder(f)
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteString("\t")
+			case "br", "cr":
+				text.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text.WriteString("\n\n")
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+	return chunkText(name, text.String()), nil
+}
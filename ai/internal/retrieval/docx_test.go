@@ -0,0 +1,45 @@
+package retrieval_test
+
+import (
+	"ai/internal/retrieval"
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeDocx writes a Word document with one paragraph per string.
+func writeDocx(t *testing.T, path string, paragraphs ...string) {
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	w := zip.NewWriter(f)
+	body, err := w.Create("word/document.xml")
+	assert.NoError(t, err)
+	xml := `<?xml version="1.0" encoding="UTF-8"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>`
+	for _, p := range paragraphs {
+		xml += `<w:p><w:r><w:t>` + p + `</w:t></w:r></w:p>`
+	}
+	xml += `</w:body></w:document>`
+	_, err = body.Write([]byte(xml))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+}
+
+func TestDocx(t *testing.T) {
+	dir := t.TempDir()
+	writeDocx(t, filepath.Join(dir, "handbook.docx"), "Thesis handbook", "The thesis defense must be scheduled two weeks ahead")
+	// a broken upload must not keep the rest out
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "broken.docx"), []byte("not a zip"), 0644))
+	r := retrieval.NewRetriever(retrieval.Config{Dir: dir})
+
+	results, err := r.Search(context.Background(), "when to schedule the defense")
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, results) {
+		assert.Equal(t, "handbook.docx", results[0].Chunk.Citation())
+		assert.Contains(t, results[0].Chunk.Text, "two weeks ahead")
+	}
+}
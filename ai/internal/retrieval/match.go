@@ -0,0 +1,49 @@
+package retrieval
+
+import (
+	"math"
+	"time"
+)
+
+// Match returns the FAQ entry whose question is most similar to question,
+// scored by the cosine of their term counts, or nil when no entry shares a
+// term with it. Word order and punctuation do not matter, so near verbatim
+// copies of a FAQ question score close to 1.
+func (x *Index) Match(question string) *Result {
+	terms := termCounts(question)
+	var best *Result
+	now := time.Now()
+	for i, q := range x.questions {
+		if q == nil || !x.chunks[i].EffectiveAt(now) {
+			continue
+		}
+		score := cosine(terms, q)
+		if score > 0 && (best == nil || score > best.Score) {
+			best = &Result{x.chunks[i], score}
+		}
+	}
+	return best
+}
+
+func termCounts(text string) map[string]int {
+	counts := map[string]int{}
+	for _, t := range Segment(text) {
+		counts[t]++
+	}
+	return counts
+}
+
+func cosine(a, b map[string]int) float64 {
+	var dot, na, nb float64
+	for t, n := range a {
+		dot += float64(n * b[t])
+		na += float64(n * n)
+	}
+	for _, n := range b {
+		nb += float64(n * n)
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,96 @@
+package retrieval
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// chunkPDF extracts the text of every page and chunks each page on its own,
+// so every chunk can be cited by page. Pages without text, e.g. scans, are
+// skipped.
+func chunkPDF(name string, data []byte) (chunks []Chunk, err error) {
+	// the reader panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			chunks, err = nil, fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	fonts := map[string]*pdf.Font{}
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				f := page.Font(name)
+				fonts[name] = &f
+			}
+		}
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", i, err)
+		}
+		for _, c := range chunkText(name, text) {
+			c.Page = i
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks, nil
+}
+
+// Citation names where a chunk comes from the way answers cite it, e.g.
+// "CLI-1.pdf p.3".
+func (c Chunk) Citation() string {
+	if c.Page > 0 {
+		return fmt.Sprintf("%s p.%d", c.Source, c.Page)
+	}
+	if c.ID != "" {
+		return c.Source + "#" + c.ID
+	}
+	return c.Source
+}
+
+// splitLong cuts a paragraph longer than maxChunkLen characters at line
+// breaks, or at spaces when a single line is too long. Text extracted from
+// PDFs often has no blank lines at all.
+func splitLong(p string) []string {
+	if len([]rune(p)) <= maxChunkLen {
+		return []string{p}
+	}
+	sep := "\n"
+	if !strings.Contains(p, sep) {
+		sep = " "
+	}
+	var parts []string
+	var current []string
+	length := 0
+	for _, piece := range strings.Split(p, sep) {
+		n := len([]rune(piece)) + 1
+		if length > 0 && length+n > maxChunkLen {
+			parts = append(parts, strings.Join(current, sep))
+			current, length = nil, 0
+		}
+		current = append(current, piece)
+		length += n
+	}
+	if len(current) > 0 {
+		parts = append(parts, strings.Join(current, sep))
+	}
+	if sep == " " {
+		return parts
+	}
+	// a single line may still be too long
+	var split []string
+	for _, part := range parts {
+		split = append(split, splitLong(part)...)
+	}
+	return split
+}
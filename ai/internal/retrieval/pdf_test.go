@@ -0,0 +1,64 @@
+package retrieval_test
+
+import (
+	"ai/internal/retrieval"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writePDF writes a PDF with one line of Helvetica text per page.
+func writePDF(t *testing.T, path string, pages ...string) {
+	var objects []string
+	kids := ""
+	for i := range pages {
+		kids += fmt.Sprintf("%d 0 R ", 4+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	for i, text := range pages {
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	assert.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func TestPDF(t *testing.T) {
+	dir := t.TempDir()
+	writePDF(t, filepath.Join(dir, "CLI-1.pdf"), "Command line basics", "The ls command lists files in a directory")
+	// an empty upload must not keep the rest out
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "empty.pdf"), nil, 0644))
+	r := retrieval.NewRetriever(retrieval.Config{Dir: dir})
+
+	results, err := r.Search(context.Background(), "which command lists files")
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, results) {
+		assert.Equal(t, 2, results[0].Chunk.Page)
+		assert.Equal(t, "CLI-1.pdf p.2", results[0].Chunk.Citation())
+		assert.Contains(t, results[0].Chunk.Text, "lists files")
+	}
+}
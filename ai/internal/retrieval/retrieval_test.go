@@ -0,0 +1,168 @@
+package retrieval_test
+
+import (
+	"ai/internal/retrieval"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const faq = `{"data": [
+	{"question": "การลาพักการศึกษาจะต้องดำเนินการอย่างไรบ้าง", "answer": "ยื่นคำร้องผ่านระบบ"},
+	{"question": "การขอสำเร็จการศึกษา จะต้องดำเนินการอย่างไรบ้าง", "answer": ["ยื่นเอกสาร", "ชำระค่าธรรมเนียม"]},
+	{"question": "Where is EN16101?", "answer": "Near the 50th anniversary building"}
+]}`
+
+func TestSegment(t *testing.T) {
+	t.Run("thai words", func(t *testing.T) {
+		assert.Equal(t, []string{"การ", "ลาพัก", "การศึกษา"}, retrieval.Segment("การลาพักการศึกษา"))
+	})
+	t.Run("mixed scripts", func(t *testing.T) {
+		assert.Equal(t, []string{"ห้อง", "en16101", "อยู่", "ที่ไหน"}, retrieval.Segment("ห้อง EN16101 อยู่ที่ไหน?"))
+	})
+	t.Run("unknown thai", func(t *testing.T) {
+		assert.Equal(t, []string{"กข", "ขค"}, retrieval.Segment("กขค"))
+	})
+}
+
+func TestRetriever(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "question.json")
+	assert.NoError(t, os.WriteFile(path, []byte(faq), 0644))
+	r := retrieval.NewRetriever(retrieval.Config{Dir: dir, TopK: 2})
+
+	t.Run("best match first", func(t *testing.T) {
+		results, err := r.Search(context.Background(), "ลาพักการศึกษาทำยังไง")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, results)
+		assert.Equal(t, "การลาพักการศึกษาจะต้องดำเนินการอย่างไรบ้าง", results[0].Chunk.Title)
+		assert.Equal(t, "question.json", results[0].Chunk.Source)
+	})
+	t.Run("no match", func(t *testing.T) {
+		results, err := r.Search(context.Background(), "parking")
+		assert.NoError(t, err)
+		assert.Empty(t, results)
+	})
+	t.Run("reloaded on change", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		assert.NoError(t, r.Watch(ctx))
+		more := filepath.Join(dir, "parking.md")
+		assert.NoError(t, os.WriteFile(more, []byte("Parking is behind building 50."), 0644))
+		assert.Eventually(t, func() bool {
+			results, err := r.Search(context.Background(), "parking")
+			return err == nil && len(results) == 1 && results[0].Chunk.Source == "parking.md"
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+	t.Run("last good version kept on bad file", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(path, []byte(`{"version": 2, "categories": []}`), 0644))
+		assert.NoError(t, r.Reload(context.Background()))
+		results, err := r.Search(context.Background(), "EN16101")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, results)
+		results, err = r.Search(context.Background(), "parking")
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+	})
+}
+
+func TestBrokenFile(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"version": 1, "categories": [`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "question.json"), []byte(faq), 0644))
+	r := retrieval.NewRetriever(retrieval.Config{Dir: dir})
+
+	// the broken file is skipped, the valid one still answers
+	results, err := r.Search(context.Background(), "ลาพักการศึกษาทำยังไง")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, results)
+	for _, result := range results {
+		assert.Equal(t, "question.json", result.Chunk.Source)
+	}
+	_, err = r.Version(context.Background())
+	assert.NoError(t, err)
+}
+
+// fixedVectors returns the same results for every query.
+type fixedVectors struct {
+	results []retrieval.Result
+	synced  int
+}
+
+func (f *fixedVectors) Sync(ctx context.Context, chunks []retrieval.Chunk) error {
+	f.synced = len(chunks)
+	return nil
+}
+
+func (f *fixedVectors) Search(ctx context.Context, query string, k int) ([]retrieval.Result, error) {
+	return f.results, nil
+}
+
+func TestHybrid(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "question.json"), []byte(faq), 0644))
+	// a paraphrase the keywords miss but the vectors find
+	paraphrase := retrieval.Chunk{Source: "question.json", Title: "Where is EN16101?", Text: "Q: Where is EN16101?\nA: Near the 50th anniversary building"}
+	vectors := &fixedVectors{results: []retrieval.Result{{Chunk: paraphrase, Score: 0.9}}}
+	r := retrieval.NewRetriever(retrieval.Config{Dir: dir, TopK: 2, Vectors: vectors})
+
+	results, err := r.Search(context.Background(), "ลาพักการศึกษา")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, vectors.synced)
+	assert.Len(t, results, 2)
+	var found []string
+	for _, result := range results {
+		found = append(found, result.Chunk.Title)
+	}
+	assert.Contains(t, found, "การลาพักการศึกษาจะต้องดำเนินการอย่างไรบ้าง")
+	assert.Contains(t, found, "Where is EN16101?")
+}
+
+func TestMatch(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "question.json"), []byte(faq), 0644))
+	r := retrieval.NewRetriever(retrieval.Config{Dir: dir})
+
+	t.Run("near copy", func(t *testing.T) {
+		match, err := r.Match(context.Background(), "การขอสำเร็จการศึกษาจะต้องดำเนินการอย่างไรบ้าง?")
+		assert.NoError(t, err)
+		if assert.NotNil(t, match) {
+			assert.Equal(t, "ยื่นเอกสาร\nชำระค่าธรรมเนียม", match.Chunk.Answer)
+		}
+	})
+	t.Run("below threshold", func(t *testing.T) {
+		match, err := r.Match(context.Background(), "ลาพักได้กี่ครั้ง")
+		assert.NoError(t, err)
+		assert.Nil(t, match)
+	})
+}
+
+func TestEffectiveDates(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "kb.json"), []byte(`{
+		"version": 2,
+		"categories": [{
+			"id": "fees",
+			"name": {"th": "ค่าธรรมเนียม"},
+			"entries": [
+				{"id": "fees-old", "question": {"th": "ค่าเทอมเท่าไหร่"}, "answer": {"th": "10000 บาท"}, "effective_until": "2000-01-01"},
+				{"id": "fees-new", "question": {"th": "ค่าเทอมเท่าไหร่"}, "answer": {"th": "12000 บาท"}, "effective_from": "2000-01-02"}
+			]
+		}]
+	}`), 0644))
+	r := retrieval.NewRetriever(retrieval.Config{Dir: dir})
+
+	match, err := r.Match(context.Background(), "ค่าเทอมเท่าไหร่")
+	assert.NoError(t, err)
+	if assert.NotNil(t, match) {
+		assert.Equal(t, "12000 บาท", match.Chunk.Answer)
+		assert.Equal(t, "fees-new", match.Chunk.ID)
+	}
+	version, err := r.Version(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "kb.json@2", version)
+}
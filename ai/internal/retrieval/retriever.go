@@ -0,0 +1,198 @@
+package retrieval
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rrfK damps the reciprocal rank fusion of lexical and vector results, 60 is
+// the value from the original paper.
+const rrfK = 60
+
+// syncRetry is how long a failed vector sync waits before it is tried again.
+const syncRetry = time.Minute
+
+// VectorIndex is a semantic index kept in sync with the knowledge files, see
+// the embedding package.
+type VectorIndex interface {
+	Sync(ctx context.Context, chunks []Chunk) error
+	Search(ctx context.Context, query string, k int) ([]Result, error)
+}
+
+type Config struct {
+	// Dir holds the knowledge files.
+	Dir string
+	// TopK is the number of chunks a search returns.
+	TopK int
+	// Vectors is optional. When set, lexical and vector results are fused.
+	Vectors VectorIndex
+	// FAQThreshold is the similarity from 0 to 1 a question needs to a FAQ
+	// question to be answered directly, see Match. Above 1 turns it off.
+	FAQThreshold float64
+}
+
+// snapshot is the knowledge of one load. It is never changed once published,
+// except for the vector sync state.
+type snapshot struct {
+	index  *Index
+	chunks []Chunk
+	// files holds the chunks by file name, for the next load to fall back on.
+	files map[string][]Chunk
+	// synced is set once the vector index holds chunks.
+	synced     atomic.Bool
+	syncFailed time.Time
+}
+
+// Retriever searches the knowledge files of a directory. They are loaded
+// once and kept in memory; Reload, or Watch on file changes, swaps in a new
+// snapshot, and searches in flight finish on the one they started with.
+type Retriever struct {
+	config   Config
+	snapshot atomic.Pointer[snapshot]
+	// reloadMu serializes loads, syncMu vector syncs.
+	reloadMu sync.Mutex
+	syncMu   sync.Mutex
+}
+
+func NewRetriever(config Config) *Retriever {
+	if config.Dir == "" {
+		config.Dir = "./assets"
+	}
+	if config.TopK == 0 {
+		config.TopK = 5
+	}
+	if config.FAQThreshold == 0 {
+		config.FAQThreshold = 0.85
+	}
+	return &Retriever{config: config}
+}
+
+// Reload reads and validates every knowledge file and swaps the result in.
+// A broken file keeps its chunks of the previous snapshot, see LoadDir. If
+// the directory cannot be read the previous snapshot keeps serving.
+func (r *Retriever) Reload(ctx context.Context) error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	var previous map[string][]Chunk
+	if s := r.snapshot.Load(); s != nil {
+		previous = s.files
+	}
+	files, err := LoadDir(r.config.Dir, previous)
+	if err != nil {
+		return err
+	}
+	chunks := flatten(files)
+	s := &snapshot{index: NewIndex(chunks), chunks: chunks, files: files}
+	r.snapshot.Store(s)
+	log.Printf("Knowledge loaded: %d chunks, version %q", s.index.Len(), s.index.Version())
+	r.syncVectors(ctx, s)
+	return nil
+}
+
+// current returns the snapshot in use, loading the first one if needed.
+func (r *Retriever) current(ctx context.Context) (*snapshot, error) {
+	if s := r.snapshot.Load(); s != nil {
+		return s, nil
+	}
+	if err := r.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return r.snapshot.Load(), nil
+}
+
+// Search returns the chunks most relevant to query, best first. If the
+// vector index fails the lexical results are returned alone.
+func (r *Retriever) Search(ctx context.Context, query string) ([]Result, error) {
+	s, err := r.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lexical := s.index.Search(query, r.config.TopK)
+	if !r.syncVectors(ctx, s) {
+		return lexical, nil
+	}
+	semantic, err := r.config.Vectors.Search(ctx, query, r.config.TopK)
+	if err != nil {
+		log.Printf("Vector search failed: %v", err)
+		return lexical, nil
+	}
+	return fuse(r.config.TopK, lexical, semantic), nil
+}
+
+// Version returns the version of the knowledge base answers are given from,
+// see Index.Version.
+func (r *Retriever) Version(ctx context.Context) (string, error) {
+	s, err := r.current(ctx)
+	if err != nil {
+		return "", err
+	}
+	return s.index.Version(), nil
+}
+
+// Match returns the FAQ entry whose question is most similar to question, or
+// nil when none reaches the FAQ threshold.
+func (r *Retriever) Match(ctx context.Context, question string) (*Result, error) {
+	s, err := r.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	best := s.index.Match(question)
+	if best == nil || best.Score < r.config.FAQThreshold {
+		return nil, nil
+	}
+	return best, nil
+}
+
+// syncVectors brings the vector index up to s unless that failed less than
+// syncRetry ago, and reports whether it can be searched.
+func (r *Retriever) syncVectors(ctx context.Context, s *snapshot) bool {
+	if r.config.Vectors == nil {
+		return false
+	}
+	if s.synced.Load() {
+		return true
+	}
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+	if s.synced.Load() || time.Since(s.syncFailed) < syncRetry || r.snapshot.Load() != s {
+		return s.synced.Load()
+	}
+	if err := r.config.Vectors.Sync(ctx, s.chunks); err != nil {
+		log.Printf("Vector index sync failed: %v", err)
+		s.syncFailed = time.Now()
+		return false
+	}
+	s.synced.Store(true)
+	return true
+}
+
+// fuse merges ranked lists by reciprocal rank fusion. Scores of the lists are
+// not comparable, ranks are. The fused score replaces the original one.
+func fuse(k int, lists ...[]Result) []Result {
+	scores := map[string]float64{}
+	var order []Chunk
+	for _, list := range lists {
+		for rank, r := range list {
+			key := r.Chunk.key()
+			if _, ok := scores[key]; !ok {
+				order = append(order, r.Chunk)
+			}
+			scores[key] += 1 / float64(rrfK+rank+1)
+		}
+	}
+	results := make([]Result, len(order))
+	for i, c := range order {
+		results[i] = Result{c, scores[c.key()]}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
@@ -0,0 +1,133 @@
+package retrieval
+
+import (
+	_ "embed"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed thai_words.txt
+var thaiWords string
+
+var defaultSegmenter = NewSegmenter(strings.Split(thaiWords, "\n"))
+
+// Segmenter splits text into search terms. Thai is written without spaces
+// between words, so Thai runs are cut by longest dictionary match and the
+// characters no word matches are indexed as bigrams. Everything else is split
+// on non letters and digits.
+type Segmenter struct {
+	words map[string]bool
+	// maxLen is the length in runes of the longest word.
+	maxLen int
+}
+
+// NewSegmenter builds a segmenter from a word list. Blank lines and lines
+// starting with # are ignored.
+func NewSegmenter(words []string) *Segmenter {
+	s := &Segmenter{words: map[string]bool{}}
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		if w == "" || strings.HasPrefix(w, "#") {
+			continue
+		}
+		s.words[w] = true
+		if n := utf8.RuneCountInString(w); n > s.maxLen {
+			s.maxLen = n
+		}
+	}
+	return s
+}
+
+// Segment returns the terms of text in order, lower cased.
+func Segment(text string) []string {
+	return defaultSegmenter.Segment(text)
+}
+
+func (s *Segmenter) Segment(text string) []string {
+	var terms []string
+	var run []rune
+	thai := false
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if thai {
+			terms = append(terms, s.segmentThai(run)...)
+		} else {
+			terms = append(terms, string(run))
+		}
+		run = run[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case isThai(r):
+			if !thai {
+				flush()
+				thai = true
+			}
+			run = append(run, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if thai {
+				flush()
+				thai = false
+			}
+			run = append(run, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+func (s *Segmenter) segmentThai(run []rune) []string {
+	var terms []string
+	var unknown []rune
+	for i := 0; i < len(run); {
+		n := s.longestMatch(run[i:])
+		if n == 0 {
+			unknown = append(unknown, run[i])
+			i++
+			continue
+		}
+		terms = append(terms, bigrams(unknown)...)
+		unknown = unknown[:0]
+		terms = append(terms, string(run[i:i+n]))
+		i += n
+	}
+	return append(terms, bigrams(unknown)...)
+}
+
+// longestMatch returns the length of the longest word at the start of run, or
+// 0 when none matches.
+func (s *Segmenter) longestMatch(run []rune) int {
+	n := s.maxLen
+	if n > len(run) {
+		n = len(run)
+	}
+	for ; n > 0; n-- {
+		if s.words[string(run[:n])] {
+			return n
+		}
+	}
+	return 0
+}
+
+// bigrams indexes text no word matched, so names and words missing from the
+// dictionary are still found. A single leftover character is dropped, it is
+// usually a vowel or tone mark.
+func bigrams(run []rune) []string {
+	if len(run) < 2 {
+		return nil
+	}
+	terms := make([]string, 0, len(run)-1)
+	for i := 0; i+1 < len(run); i++ {
+		terms = append(terms, string(run[i:i+2]))
+	}
+	return terms
+}
+
+func isThai(r rune) bool {
+	return r >= 0x0E00 && r <= 0x0E7F && !unicode.IsDigit(r)
+}
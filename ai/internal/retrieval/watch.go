@@ -0,0 +1,57 @@
+package retrieval
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// settle is how long Watch waits after the last change before reloading, so
+// an editor or upload writing a file in several steps causes one reload.
+const settle = 300 * time.Millisecond
+
+// Watch reloads the knowledge whenever a knowledge file in the directory is
+// created, written, renamed or removed, until ctx is done. A reload that
+// fails validation is logged and the previous snapshot keeps serving.
+func (r *Retriever) Watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(r.config.Dir); err != nil {
+		watcher.Close()
+		return err
+	}
+	go func() {
+		defer watcher.Close()
+		timer := time.NewTimer(settle)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if indexable(filepath.Base(event.Name)) && event.Op != fsnotify.Chmod {
+					timer.Reset(settle)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Knowledge watcher: %v", err)
+			case <-timer.C:
+				if err := r.Reload(ctx); err != nil {
+					log.Printf("Keeping the previous knowledge: %v", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
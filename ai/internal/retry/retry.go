@@ -0,0 +1,184 @@
+// Package retry decides what the fallback loop does after a model call
+// fails: call the same model again after a backoff, cool the model or key
+// down and move on, skip the model for this question only, or give up.
+package retry
+
+import (
+	"ai/internal/health"
+	"ai/internal/provider"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// Class is the kind of error a call failed with.
+type Class string
+
+const (
+	// Transient errors are 5xx responses, timeouts and dropped connections.
+	Transient Class = "transient"
+	// Quota is a key that ran out of quota or hit a rate limit.
+	Quota Class = "quota"
+	// Key is a key that was rejected.
+	Key Class = "key"
+	// Invalid is a request the provider refused, e.g. 400 Bad Request; another
+	// provider may still take it.
+	Invalid Class = "invalid"
+	// Blocked is a question or answer blocked for safety.
+	Blocked Class = "blocked"
+	// Other is any error not told apart above.
+	Other Class = "other"
+)
+
+// Action is what is done after a failed call.
+type Action string
+
+const (
+	// Retry calls the same model with the same key again after a backoff.
+	// Once Policy.Retries are used up the model is cooled down instead.
+	Retry Action = "retry"
+	// CoolDown reports the failure to the health registry, which cools down
+	// the key or the model for every request, and moves on.
+	CoolDown Action = "cool_down"
+	// Skip leaves the model out for this question only.
+	Skip Action = "skip"
+	// Stop gives up on the question.
+	Stop Action = "stop"
+)
+
+// ParseAction reads an action from the configuration.
+func ParseAction(s string) (Action, error) {
+	switch a := Action(s); a {
+	case Retry, CoolDown, Skip, Stop:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown retry action %q", s)
+}
+
+// Classify tells what kind of error err is.
+func Classify(err error) Class {
+	if errors.Is(err, provider.ErrBlocked) {
+		return Blocked
+	}
+	if health.IsQuotaError(err) {
+		return Quota
+	}
+	if health.IsKeyError(err) {
+		return Key
+	}
+	switch code := health.StatusCode(err); {
+	case code >= 500 || code == http.StatusRequestTimeout:
+		return Transient
+	case code >= 400:
+		return Invalid
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return Transient
+	}
+	return Other
+}
+
+// DefaultActions retry transient errors, cool down keys and models that
+// fail otherwise, skip the model for a refused request and give up on
+// blocked ones, which are answered with the refused notice.
+var DefaultActions = map[Class]Action{
+	Transient: Retry,
+	Quota:     CoolDown,
+	Key:       CoolDown,
+	Invalid:   Skip,
+	Blocked:   Stop,
+	Other:     CoolDown,
+}
+
+type Policy struct {
+	// MaxAttempts bounds the model calls for one question, 6 by default.
+	MaxAttempts int
+	// Retries is how often Retry calls the same model again, 1 by default
+	// and none when negative.
+	Retries int
+	// BaseDelay is the longest first backoff, doubled for every retry up to
+	// MaxDelay; 200ms and 2s by default. The backoff is a random duration
+	// up to that, so replicas do not retry in step.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Actions overrides DefaultActions per class.
+	Actions map[Class]Action
+	// Rand returns a number in [0, 1) for the jitter, rand.Float64 by default.
+	Rand func() float64
+}
+
+func (p Policy) WithDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 6
+	}
+	switch {
+	case p.Retries < 0:
+		p.Retries = 0
+	case p.Retries == 0:
+		p.Retries = 1
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = 200 * time.Millisecond
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 2 * time.Second
+	}
+	actions := make(map[Class]Action, len(DefaultActions))
+	for class, action := range DefaultActions {
+		actions[class] = action
+	}
+	for class, action := range p.Actions {
+		actions[class] = action
+	}
+	p.Actions = actions
+	if p.Rand == nil {
+		p.Rand = rand.Float64
+	}
+	return p
+}
+
+// Decide returns what to do after a call failed with class, when the same
+// model was already retried retries times.
+func (p Policy) Decide(class Class, retries int) Action {
+	action, ok := p.Actions[class]
+	if !ok {
+		action = CoolDown
+	}
+	if action == Retry && retries >= p.Retries {
+		return CoolDown
+	}
+	return action
+}
+
+// Backoff is the wait before retry number retry, counted from 0.
+func (p Policy) Backoff(retry int) time.Duration {
+	limit := p.BaseDelay
+	for i := 0; i < retry && limit < p.MaxDelay; i++ {
+		limit *= 2
+	}
+	limit = min(limit, p.MaxDelay)
+	return time.Duration(p.Rand() * float64(limit))
+}
+
+// Sleep waits for d, or until ctx is done.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,95 @@
+package retry_test
+
+import (
+	"ai/internal/provider"
+	"ai/internal/retry"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func statusError(code int, body string) error {
+	return &provider.StatusError{Provider: "chatgpt", StatusCode: code, Body: body}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want retry.Class
+	}{
+		{"server error", statusError(http.StatusServiceUnavailable, "overloaded"), retry.Transient},
+		{"request timeout", statusError(http.StatusRequestTimeout, ""), retry.Transient},
+		{"deadline", fmt.Errorf("HTTP request failed: %w", context.DeadlineExceeded), retry.Transient},
+		{"dropped connection", fmt.Errorf("failed to read: %w", io.ErrUnexpectedEOF), retry.Transient},
+		{"rate limit", statusError(http.StatusTooManyRequests, ""), retry.Quota},
+		{"quota in the text", errors.New("googleapi: Error 429: RESOURCE_EXHAUSTED"), retry.Quota},
+		{"rejected key", statusError(http.StatusUnauthorized, "invalid api key"), retry.Key},
+		{"bad request", statusError(http.StatusBadRequest, "response_format is not supported"), retry.Invalid},
+		{"blocked", fmt.Errorf("%w: blocked: candidate: FinishReasonSafety", provider.ErrBlocked), retry.Blocked},
+		{"other", errors.New("no candidates found"), retry.Other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, retry.Classify(tt.err))
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		p := retry.Policy{}.WithDefaults()
+		assert.Equal(t, retry.Retry, p.Decide(retry.Transient, 0))
+		assert.Equal(t, retry.CoolDown, p.Decide(retry.Transient, 1))
+		assert.Equal(t, retry.CoolDown, p.Decide(retry.Quota, 0))
+		assert.Equal(t, retry.Skip, p.Decide(retry.Invalid, 0))
+		assert.Equal(t, retry.Stop, p.Decide(retry.Blocked, 0))
+		assert.Equal(t, retry.CoolDown, p.Decide(retry.Class("unheard of"), 0))
+	})
+	t.Run("overrides", func(t *testing.T) {
+		p := retry.Policy{Retries: 3, Actions: map[retry.Class]retry.Action{retry.Invalid: retry.Stop}}.WithDefaults()
+		assert.Equal(t, retry.Retry, p.Decide(retry.Transient, 2))
+		assert.Equal(t, retry.Stop, p.Decide(retry.Invalid, 0))
+		assert.Equal(t, retry.Skip, retry.DefaultActions[retry.Invalid])
+	})
+	t.Run("no retries", func(t *testing.T) {
+		p := retry.Policy{Retries: -1}.WithDefaults()
+		assert.Equal(t, retry.CoolDown, p.Decide(retry.Transient, 0))
+	})
+	t.Run("backoff", func(t *testing.T) {
+		p := retry.Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Rand: func() float64 { return 0.5 }}.WithDefaults()
+		assert.Equal(t, 50*time.Millisecond, p.Backoff(0))
+		assert.Equal(t, 100*time.Millisecond, p.Backoff(1))
+		assert.Equal(t, 200*time.Millisecond, p.Backoff(2))
+		assert.Equal(t, 500*time.Millisecond, p.Backoff(10))
+	})
+	t.Run("jitter", func(t *testing.T) {
+		p := retry.Policy{}.WithDefaults()
+		for i := 0; i < 100; i++ {
+			d := p.Backoff(1)
+			assert.GreaterOrEqual(t, d, time.Duration(0))
+			assert.Less(t, d, 400*time.Millisecond)
+		}
+	})
+}
+
+func TestParseAction(t *testing.T) {
+	action, err := retry.ParseAction("cool_down")
+	assert.NoError(t, err)
+	assert.Equal(t, retry.CoolDown, action)
+	_, err = retry.ParseAction("later")
+	assert.Error(t, err)
+}
+
+func TestSleep(t *testing.T) {
+	assert.NoError(t, retry.Sleep(context.Background(), time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, retry.Sleep(ctx, time.Minute), context.Canceled)
+}
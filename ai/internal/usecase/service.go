@@ -3,10 +3,13 @@ package usecase
 import (
 	"ai/api/pb"
 	"ai/internal/repository"
+	"context"
 )
 
 type AiUsecase interface {
-	Ask(*pb.AiRequest) (*pb.AiResponse, error)
+	Ask(context.Context, *pb.AiRequest) (*pb.AiResponse, error)
+	AskStream(context.Context, *pb.AiRequest, func(*pb.AiStreamResponse) error) error
+	GetHealth(context.Context) (*pb.HealthResponse, error)
 }
 type aiUsecase struct {
 	repository repository.AiRepository
@@ -15,6 +18,12 @@ type aiUsecase struct {
 func NewAiService(repository repository.AiRepository) AiUsecase {
 	return &aiUsecase{repository}
 }
-func (a *aiUsecase) Ask(req *pb.AiRequest) (*pb.AiResponse, error) {
-	return a.repository.Ask(req)
+func (a *aiUsecase) Ask(ctx context.Context, req *pb.AiRequest) (*pb.AiResponse, error) {
+	return a.repository.Ask(ctx, req)
+}
+func (a *aiUsecase) AskStream(ctx context.Context, req *pb.AiRequest, send func(*pb.AiStreamResponse) error) error {
+	return a.repository.AskStream(ctx, req, send)
+}
+func (a *aiUsecase) GetHealth(ctx context.Context) (*pb.HealthResponse, error) {
+	return a.repository.GetHealth(ctx)
 }
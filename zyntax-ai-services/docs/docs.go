@@ -22,9 +22,10 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Ask a question to the AI service",
+                "description": "Ask a question to the AI service.\nThe X-Answer-Source header is faq when the answer is the curated one of the official FAQ, model when generated and search when looked up on the web.\nSend multipart/form-data with question, history_id and an optional image file (jpeg, png, webp or gif) to ask about a photo.\nSet lang (th or en, in the body or the query) to be answered in that language only; otherwise the answer has both, labelled \"ภาษาไทย:\" and \"English:\".",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
@@ -47,6 +48,95 @@ const docTemplate = `{
                 "responses": {}
             }
         },
+        "/api/v1/ask/handoffs": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the questions the AI did not know and passed to staff, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Handoff"
+                ],
+                "summary": "Get handoff tickets",
+                "parameters": [
+                    {
+                        "enum": [
+                            "open",
+                            "closed"
+                        ],
+                        "type": "string",
+                        "description": "open or closed, all when empty",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/ask.HandoffTicket"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/ask/handoffs/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Close a handoff ticket with the answer of staff, which replaces the answer in the history of the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Handoff"
+                ],
+                "summary": "Reply to a handoff ticket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Ticket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Answer of staff",
+                        "name": "reply",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/ask.HandoffReply"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ask.HandoffTicket"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/ask/history": {
             "get": {
                 "security": [
@@ -105,7 +195,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get history messages by history ID",
+                "description": "Get history messages by history ID, every answer with the citations of the official sources it was given from. Only the owner of the history and admins may read it.",
                 "consumes": [
                     "application/json"
                 ],
@@ -195,6 +285,69 @@ const docTemplate = `{
                 "responses": {}
             }
         },
+        "/api/v1/ask/images/{name}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a photo that was sent with a question, by the name stored in the history message. Only the owner of the history and admins may read it.",
+                "produces": [
+                    "image/jpeg",
+                    "image/png",
+                    "image/webp",
+                    "image/gif"
+                ],
+                "tags": [
+                    "Ask"
+                ],
+                "summary": "Get an image of a question",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Image name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/api/v1/ask/stream": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Ask a question to the AI service and receive the answer as Server-Sent Events.\nEvery event carries an AiStreamResponse; the last one has done=true with the model, finish reason, source (faq, model, search or handoff), knowledge version, prompt version, outcome (answered, unknown or refused), escalation, citations and the answer split into answer_th and answer_en; with debug, admins also get the model calls made as attempts.\nAccepts the same JSON or multipart/form-data body as /api/v1/ask/.",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Ask"
+                ],
+                "summary": "Ask a question and stream the answer",
+                "parameters": [
+                    {
+                        "description": "Question to ask",
+                        "name": "question",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/ask.Ask"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
         "/api/v1/auth/": {
             "post": {
                 "description": "https://oauth.kku.ac.th/authorize?response_type=code\u0026client_id=e8fdb4894be17a3a\u0026redirect_uri=http://localhost:8080/api/v1/swagger/index.html",
@@ -248,7 +401,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Create a new file",
+                "description": "Upload a knowledge file: .json, .txt, .md (2MB) or .pdf, .docx (10MB). It is stored under a generated name. JSON that does not parse, or a knowledge base without a version or categories, is refused with 400.",
                 "consumes": [
                     "multipart/form-data"
                 ],
@@ -306,7 +459,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Update a file",
+                "description": "Rename a file. Only Name is read, the stored contents and their Path cannot be changed.",
                 "consumes": [
                     "application/json"
                 ],
@@ -365,20 +518,145 @@ const docTemplate = `{
                 ],
                 "responses": {}
             }
+        },
+        "/api/v1/files/{id}/audits": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Who uploaded, updated or deleted the file, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Get the audit trail of a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.FileAudit"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/files/{id}/content": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Download the contents of a file",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Download the contents of a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
         }
     },
     "definitions": {
         "ask.Ask": {
             "type": "object",
             "properties": {
+                "debug": {
+                    "description": "Debug returns the model calls made for the answer, for admins only.",
+                    "type": "boolean"
+                },
                 "history_id": {
                     "type": "integer"
                 },
+                "lang": {
+                    "description": "Lang is th or en to be answered in that language only, both when empty.",
+                    "type": "string",
+                    "enum": [
+                        "th",
+                        "en"
+                    ]
+                },
                 "question": {
                     "type": "string"
                 }
             }
         },
+        "ask.HandoffReply": {
+            "type": "object",
+            "properties": {
+                "reply": {
+                    "type": "string"
+                }
+            }
+        },
+        "ask.HandoffTicket": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "history_message": {
+                    "$ref": "#/definitions/ask.HistoryMessage"
+                },
+                "history_message_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "main_user_id": {
+                    "description": "MainUserID asked the question.",
+                    "type": "string"
+                },
+                "reply": {
+                    "description": "Reply is the answer of staff, ResolvedBy the ID of who gave it.",
+                    "type": "string"
+                },
+                "resolved_at": {
+                    "type": "string"
+                },
+                "resolved_by": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "open",
+                        "closed"
+                    ]
+                }
+            }
+        },
         "ask.History": {
             "type": "object",
             "properties": {
@@ -387,16 +665,133 @@ const docTemplate = `{
                 }
             }
         },
+        "ask.HistoryCitation": {
+            "type": "object",
+            "properties": {
+                "entry_id": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "ask.HistoryMessage": {
+            "type": "object",
+            "properties": {
+                "answer": {
+                    "type": "string"
+                },
+                "answer_en": {
+                    "type": "string"
+                },
+                "answer_th": {
+                    "description": "AnswerTh and AnswerEn hold the languages of Answer apart, one is empty\nwhen only the other was asked for.",
+                    "type": "string"
+                },
+                "citations": {
+                    "description": "Citations are the official sources that back the answer.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ask.HistoryCitation"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "escalation": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "image": {
+                    "description": "Image is the name of the photo sent with the question, see GetImage.",
+                    "type": "string"
+                },
+                "image_mime_type": {
+                    "type": "string"
+                },
+                "knowledge_version": {
+                    "description": "KnowledgeVersion is the version of the knowledge base the answer was\ngiven from.",
+                    "type": "string"
+                },
+                "outcome": {
+                    "description": "Outcome is answered, unknown or refused and Escalation how an unknown\nanswer was followed up, see AiResponse.",
+                    "type": "string"
+                },
+                "prompt_version": {
+                    "description": "PromptVersion tells which prompt templates produced the answer.",
+                    "type": "string"
+                },
+                "question": {
+                    "type": "string"
+                },
+                "source": {
+                    "description": "Source is faq when the answer was taken from the official FAQ, see\nAiResponse.",
+                    "type": "string"
+                }
+            }
+        },
         "models.File": {
             "type": "object",
             "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "hash": {
+                    "description": "Hash is the hex SHA-256 of the contents.",
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "mimeType": {
+                    "type": "string"
+                },
                 "name": {
                     "type": "string"
                 },
                 "path": {
+                    "description": "Path is the key of the contents in the storage backend.",
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "uploadedBy": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FileAudit": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "file_id": {
+                    "type": "integer"
+                },
+                "file_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "user_id": {
                     "type": "string"
                 }
             }
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/golang-jwt/jwt/v4"
@@ -46,13 +47,14 @@ func ExtractSocketToken(authHeader string) (token string, err error) {
 	return
 }
 
+// ExtractLevel reads the level of an audience like "admin:5".
 func ExtractLevel(aud []string) (level int, err error) {
 	if len(aud) < 1 {
 		err = helpers.NewError(http.StatusBadRequest, helpers.WhereAmI(), "aud field missmatch")
 		return
 	}
 	levels := strings.Split(aud[0], ":")
-	if len(levels) < 1 {
+	if len(levels) < 2 {
 		err = helpers.NewError(http.StatusBadRequest, helpers.WhereAmI(), "levels field missmatch")
 		return
 	}
@@ -60,6 +62,23 @@ func ExtractLevel(aud []string) (level int, err error) {
 	return strconv.Atoi(levels[1])
 }
 
+// extractLevel reads the level of the token, checking an admin level against
+// the level the user has now, see RouterResources.Level.
+func (r *RouterResources) extractLevel(claims *jwt.RegisteredClaims) (int, error) {
+	level, err := ExtractLevel(claims.Audience)
+	if err != nil || level < models.LevelAdmin || r.Level == nil {
+		return level, err
+	}
+	current, err := r.Level(claims.Subject)
+	if err != nil {
+		return 0, err
+	}
+	if current < level {
+		return current, nil
+	}
+	return level, nil
+}
+
 // ReqLineAuthHandler check session
 func (r *RouterResources) ReqAuthHandler(reqLevels ...int) fiber.Handler {
 	reqLevel := 4
@@ -80,7 +99,7 @@ func (r *RouterResources) ReqAuthHandler(reqLevels ...int) fiber.Handler {
 			return helpers.NewError(http.StatusUnauthorized, helpers.WhereAmI(), err.Error())
 		}
 		if jwtToken != nil && jwtToken.Valid {
-			if level, err := ExtractLevel(claims.Audience); err != nil {
+			if level, err := r.extractLevel(claims); err != nil {
 				return helpers.NewError(http.StatusUnauthorized, helpers.WhereAmI(), err.Error())
 			} else if level < reqLevel {
 				return helpers.NewError(http.StatusForbidden, helpers.WhereAmI(), fmt.Sprintf("%s need permission level %d", c.Route().Path, reqLevel))
@@ -0,0 +1,71 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/internal/handlers"
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	helpers "github.com/zercle/gofiber-helpers"
+)
+
+func TestExtractLevel(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		level, err := handlers.ExtractLevel([]string{"admin:5"})
+		assert.NoError(t, err)
+		assert.Equal(t, 5, level)
+	})
+	t.Run("without level", func(t *testing.T) {
+		_, err := handlers.ExtractLevel([]string{"admin"})
+		assert.Error(t, err)
+	})
+	t.Run("without audience", func(t *testing.T) {
+		_, err := handlers.ExtractLevel(nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestReqAuthHandler(t *testing.T) {
+	key := []byte("secret")
+	sign := func(audience string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.RegisteredClaims{
+			Subject:   "somchai",
+			Audience:  []string{audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		})
+		signed, err := token.SignedString(key)
+		assert.NoError(t, err)
+		return signed
+	}
+	get := func(level func(string) (int, error), audience string) int {
+		resources := handlers.NewRouterResources(func(*jwt.Token) (interface{}, error) { return key, nil }, level)
+		app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+			// like the server's, which answers with the code of a helpers.Error
+			return c.SendStatus(err.(*helpers.Error).Code)
+		}})
+		app.Get("/admin", resources.ReqAuthHandler(models.LevelAdmin), func(c *fiber.Ctx) error {
+			return c.SendStatus(http.StatusOK)
+		})
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+sign(audience))
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+	admin := func(string) (int, error) { return models.LevelAdmin, nil }
+	demoted := func(string) (int, error) { return models.LevelUser, nil }
+
+	assert.Equal(t, http.StatusOK, get(admin, "admin:5"))
+	assert.Equal(t, http.StatusOK, get(nil, "admin:5"))
+	assert.Equal(t, http.StatusForbidden, get(admin, "user:1"))
+	// the token still says admin, the user is not one any more
+	assert.Equal(t, http.StatusForbidden, get(demoted, "admin:5"))
+	assert.Equal(t, http.StatusForbidden, get(demoted, "highest permission:5"))
+	assert.Equal(t, http.StatusUnauthorized, get(func(string) (int, error) { return 0, errors.New("record not found") }, "admin:5"))
+}
@@ -5,11 +5,16 @@ import "github.com/golang-jwt/jwt/v4"
 // RouterResources DB handler
 type RouterResources struct {
 	JwtKeyfunc jwt.Keyfunc
+	// Level, when set, returns the level a user has now. ReqAuthHandler
+	// checks admin tokens against it, so a demoted admin or a token of an
+	// older format is not trusted until it expires.
+	Level func(userID string) (int, error)
 }
 
 // NewRouterResources returns a new DBHandler
-func NewRouterResources(jwtKeyfunc jwt.Keyfunc) *RouterResources {
+func NewRouterResources(jwtKeyfunc jwt.Keyfunc, level func(userID string) (int, error)) *RouterResources {
 	return &RouterResources{
 		JwtKeyfunc: jwtKeyfunc,
+		Level:      level,
 	}
 }
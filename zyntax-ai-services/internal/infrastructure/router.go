@@ -23,17 +23,17 @@ func (s *Server) SetupRoutes(app *fiber.App) {
 	}
 	app.Get("/api/v1/swagger/*", swagger.HandlerDefault)
 	s.MainDbConn.AutoMigrate(&models.MainUser{})
-	s.MainDbConn.AutoMigrate(&ask.History{}, &ask.HistoryMessage{})
-	s.MainDbConn.AutoMigrate(&ask.MapHistoryMessage{}, &ask.MapUserHistory{})
-	s.MainDbConn.AutoMigrate(&models.File{})
-	routerResource := handlers.NewRouterResources(s.JwtResources.JwtKeyfunc)
+	s.MainDbConn.AutoMigrate(&ask.History{}, &ask.HistoryMessage{}, &ask.HistoryCitation{})
+	s.MainDbConn.AutoMigrate(&ask.MapHistoryMessage{}, &ask.MapUserHistory{}, &ask.HandoffTicket{})
+	s.MainDbConn.AutoMigrate(&models.File{}, &models.FileAudit{})
 	fileRepository := file.NewFileRepository(s.MainDbConn)
 	authRepository := auth.NewAuthRepository(s.MainDbConn)
 	authService := auth.NewAuthService(authRepository)
-	fileService := file.NewFileService(fileRepository)
+	routerResource := handlers.NewRouterResources(s.JwtResources.JwtKeyfunc, authService.Level)
+	fileService := file.NewFileService(fileRepository, s.Storage)
 	ask.NewAskHandler(app.Group("/api/v1/ask"), routerResource, ":50051", s.MainDbConn)
 	auth.NewAuthHandler(app.Group("/api/v1/auth"), authService, s.JwtResources)
-	file.NewFileHandler(app.Group("/api/v1/files"), fileService)
+	file.NewFileHandler(app.Group("/api/v1/files"), routerResource, fileService)
 	// Prepare a fallback route to always serve the 'index.html', had there not be any matching routes.
 	app.Static("*", "./web/build/index.html")
 }
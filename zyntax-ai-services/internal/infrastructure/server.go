@@ -18,6 +18,7 @@ import (
 	"github.com/Zentrix-Software-Hive/zyntax-ai-services/internal/datasources"
 	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/logs"
 	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/storage"
 	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/utils"
 )
 
@@ -27,6 +28,8 @@ type Server struct {
 	Build   string
 	RunEnv  string
 	PrdMode bool
+	// Storage keeps the contents of uploaded files.
+	Storage storage.Storage
 }
 
 func NewServer(version, buildTag, runEnv string) (server *Server, err error) {
@@ -72,6 +75,10 @@ func NewServer(version, buildTag, runEnv string) (server *Server, err error) {
 	server.Resources = NewResources(fastHTTPClient, mainDbConn, nil, nil, jwtResources)
 
 	// something that use resources place here
+	server.Storage, err = storage.NewFromConfig()
+	if err != nil {
+		return
+	}
 
 	// pre config server
 	err = server.configApp()
@@ -89,6 +96,8 @@ func (s *Server) Run() (err error) {
 		ReadBufferSize:    8 * 1024,
 		Prefork:           s.PrdMode,
 		StreamRequestBody: true,
+		// questions may carry a photo, 0 keeps fiber's 4MB default
+		BodyLimit:         viper.GetInt("app.body_limit"),
 		// speed up json with goccy/go-json
 		JSONEncoder: json.Marshal,
 		JSONDecoder: json.Unmarshal,
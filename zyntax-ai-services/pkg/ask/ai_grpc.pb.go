@@ -19,7 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	AiService_Ask_FullMethodName = "/ai.api.proto.AiService/Ask"
+	AiService_Ask_FullMethodName       = "/ai.api.proto.AiService/Ask"
+	AiService_AskStream_FullMethodName = "/ai.api.proto.AiService/AskStream"
+	AiService_GetHealth_FullMethodName = "/ai.api.proto.AiService/GetHealth"
 )
 
 // AiServiceClient is the client API for AiService service.
@@ -27,6 +29,12 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type AiServiceClient interface {
 	Ask(ctx context.Context, in *AiRequest, opts ...grpc.CallOption) (*AiResponse, error)
+	AskStream(ctx context.Context, in *AiRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AiStreamResponse], error)
+	// GetHealth lists the models and API keys that are cooling down and the
+	// usage of every key. It is internal-only: callers must send the
+	// metadata "authorization: Bearer <admin.token>", and it is disabled
+	// while admin.token is not set.
+	GetHealth(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
 }
 
 type aiServiceClient struct {
@@ -47,11 +55,46 @@ func (c *aiServiceClient) Ask(ctx context.Context, in *AiRequest, opts ...grpc.C
 	return out, nil
 }
 
+func (c *aiServiceClient) AskStream(ctx context.Context, in *AiRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AiStreamResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &AiService_ServiceDesc.Streams[0], AiService_AskStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[AiRequest, AiStreamResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AiService_AskStreamClient = grpc.ServerStreamingClient[AiStreamResponse]
+
+func (c *aiServiceClient) GetHealth(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HealthResponse)
+	err := c.cc.Invoke(ctx, AiService_GetHealth_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AiServiceServer is the server API for AiService service.
 // All implementations must embed UnimplementedAiServiceServer
 // for forward compatibility.
 type AiServiceServer interface {
 	Ask(context.Context, *AiRequest) (*AiResponse, error)
+	AskStream(*AiRequest, grpc.ServerStreamingServer[AiStreamResponse]) error
+	// GetHealth lists the models and API keys that are cooling down and the
+	// usage of every key. It is internal-only: callers must send the
+	// metadata "authorization: Bearer <admin.token>", and it is disabled
+	// while admin.token is not set.
+	GetHealth(context.Context, *HealthRequest) (*HealthResponse, error)
 	mustEmbedUnimplementedAiServiceServer()
 }
 
@@ -65,6 +108,12 @@ type UnimplementedAiServiceServer struct{}
 func (UnimplementedAiServiceServer) Ask(context.Context, *AiRequest) (*AiResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Ask not implemented")
 }
+func (UnimplementedAiServiceServer) AskStream(*AiRequest, grpc.ServerStreamingServer[AiStreamResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method AskStream not implemented")
+}
+func (UnimplementedAiServiceServer) GetHealth(context.Context, *HealthRequest) (*HealthResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHealth not implemented")
+}
 func (UnimplementedAiServiceServer) mustEmbedUnimplementedAiServiceServer() {}
 func (UnimplementedAiServiceServer) testEmbeddedByValue()                   {}
 
@@ -104,6 +153,35 @@ func _AiService_Ask_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AiService_AskStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AiRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AiServiceServer).AskStream(m, &grpc.GenericServerStream[AiRequest, AiStreamResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type AiService_AskStreamServer = grpc.ServerStreamingServer[AiStreamResponse]
+
+func _AiService_GetHealth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AiServiceServer).GetHealth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AiService_GetHealth_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AiServiceServer).GetHealth(ctx, req.(*HealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AiService_ServiceDesc is the grpc.ServiceDesc for AiService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -115,7 +193,17 @@ var AiService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Ask",
 			Handler:    _AiService_Ask_Handler,
 		},
+		{
+			MethodName: "GetHealth",
+			Handler:    _AiService_GetHealth_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "AskStream",
+			Handler:       _AiService_AskStream_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/proto/ai.proto",
 }
@@ -1,8 +1,11 @@
 package ask
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -10,9 +13,13 @@ import (
 	"time"
 
 	"github.com/Zentrix-Software-Hive/zyntax-ai-services/internal/handlers"
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
 	"github.com/gofiber/fiber/v2"
+	"github.com/spf13/viper"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -28,11 +35,15 @@ func NewAskHandler(askRoute fiber.Router, auth *handlers.RouterResources, grpcAd
 		log.Fatalf("Failed to create ask handler: %v", err)
 	}
 	askRoute.Post("/", auth.ReqAuthHandler(0), handler.Ask)
+	askRoute.Post("/stream", auth.ReqAuthHandler(0), handler.AskStream)
+	askRoute.Get("/images/:name", auth.ReqAuthHandler(0), handler.GetImage)
 	askRoute.Post("/history", auth.ReqAuthHandler(0), handler.CreateHistoryMe)
 	askRoute.Get("/history", auth.ReqAuthHandler(0), handler.GetHistoriesMe)
 	askRoute.Get("/history/messages/:id", auth.ReqAuthHandler(0), handler.GetHistoryMessageByHistoryID)
 	askRoute.Put("/history/:id", auth.ReqAuthHandler(0), handler.UpdatePlaceholder)
 	askRoute.Delete("/history/:id", auth.ReqAuthHandler(0), handler.DeleteHistory)
+	askRoute.Get("/handoffs", auth.ReqAuthHandler(models.LevelAdmin), handler.GetHandoffs)
+	askRoute.Put("/handoffs/:id", auth.ReqAuthHandler(models.LevelAdmin), handler.ResolveHandoff)
 }
 
 func newAskHandler(grpcAddress string, db *gorm.DB) (*askHandler, error) {
@@ -48,8 +59,12 @@ func newAskHandler(grpcAddress string, db *gorm.DB) (*askHandler, error) {
 }
 
 type Ask struct {
-	Question  string `json:"question"`
-	HistoryId int    `json:"history_id"`
+	Question  string `json:"question" form:"question"`
+	HistoryId int    `json:"history_id" form:"history_id"`
+	// Lang is th or en to be answered in that language only, both when empty.
+	Lang string `json:"lang,omitempty" form:"lang" enums:"th,en"`
+	// Debug returns the model calls made for the answer, for admins only.
+	Debug bool `json:"debug,omitempty" form:"debug"`
 }
 
 type History struct {
@@ -64,11 +79,59 @@ type MapHistoryMessage struct {
 	Message   HistoryMessage `json:"message" gorm:"foreignKey:MessageId;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 }
 type HistoryMessage struct {
-	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
-	Question  string    `json:"question"`
-	Answer    string    `json:"answer"`
-	CreatedAt time.Time `json:"created_at"`
+	ID       int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	// AnswerTh and AnswerEn hold the languages of Answer apart, one is empty
+	// when only the other was asked for.
+	AnswerTh string `json:"answer_th,omitempty"`
+	AnswerEn string `json:"answer_en,omitempty"`
+	// Image is the name of the photo sent with the question, see GetImage.
+	Image         string `json:"image,omitempty"`
+	ImageMimeType string `json:"image_mime_type,omitempty"`
+	// Source is faq when the answer was taken from the official FAQ, see
+	// AiResponse.
+	Source string `json:"source,omitempty"`
+	// KnowledgeVersion is the version of the knowledge base the answer was
+	// given from.
+	KnowledgeVersion string `json:"knowledge_version,omitempty"`
+	// PromptVersion tells which prompt templates produced the answer.
+	PromptVersion string `json:"prompt_version,omitempty"`
+	// Outcome is answered, unknown or refused and Escalation how an unknown
+	// answer was followed up, see AiResponse.
+	Outcome    string `json:"outcome,omitempty"`
+	Escalation string `json:"escalation,omitempty"`
+	// Citations are the official sources that back the answer.
+	Citations []HistoryCitation `json:"citations" gorm:"foreignKey:HistoryMessageID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt time.Time         `json:"created_at"`
 }
+
+// HistoryCitation is a knowledge base entry, a page of a document or a web
+// page an answer was given from, see Citation.
+type HistoryCitation struct {
+	ID               int    `json:"id" gorm:"primaryKey;autoIncrement" swaggerignore:"true"`
+	HistoryMessageID int    `json:"-" gorm:"index"`
+	Source           string `json:"source,omitempty"`
+	EntryID          string `json:"entry_id,omitempty"`
+	Page             int    `json:"page,omitempty"`
+	Title            string `json:"title,omitempty"`
+	URL              string `json:"url,omitempty"`
+}
+
+func toHistoryCitations(citations []*Citation) []HistoryCitation {
+	var res []HistoryCitation
+	for _, c := range citations {
+		res = append(res, HistoryCitation{
+			Source:  c.GetSource(),
+			EntryID: c.GetEntryId(),
+			Page:    int(c.GetPage()),
+			Title:   c.GetTitle(),
+			URL:     c.GetUrl(),
+		})
+	}
+	return res
+}
+
 type MapUserHistory struct {
 	ID         int      `json:"id" gorm:"primaryKey;autoIncrement"`
 	MainUserID string   `json:"main_user_id"`
@@ -83,23 +146,10 @@ type MainUser struct {
 	Email  string `json:"email"`
 }
 
-// @Summary Ask a question
-// @Description Ask a question to the AI service
-// @Tags Ask
-// @Accept json
-// @Produce json
-// @Param question body Ask true "Question to ask"
-// @Router /api/v1/ask/ [post]
-// @Security ApiKeyAuth
-func (h *askHandler) Ask(c *fiber.Ctx) error {
-	ask := Ask{}
-	err := c.BodyParser(&ask)
-	if err != nil {
-		return c.Status(http.StatusBadRequest).SendString("Invalid request body")
-	}
-	if ask.Question == "" {
-		return c.Status(http.StatusBadRequest).SendString("Query parameter 'question' is required")
-	}
+// feeTableURL is returned instead of asking the AI when the question is about fees.
+const feeTableURL = "https://img2.pic.in.th/pic/.-650x900.png"
+
+func isFeeQuestion(question string) bool {
 	isFeeList := []string{
 		"ค่าธรรมเนียม", // fee
 		"ค่าเทอม",      // tuition fee
@@ -111,65 +161,311 @@ func (h *askHandler) Ask(c *fiber.Ctx) error {
 		"registration fee",
 	}
 	for _, fee := range isFeeList {
-		if strings.Contains(strings.ToLower(ask.Question), fee) {
-			//save message
-			historyMessage := HistoryMessage{
-				Question:  ask.Question,
-				Answer:    "https://img2.pic.in.th/pic/.-650x900.png",
-				CreatedAt: time.Now(),
-			}
-			if err := h.db.Create(&historyMessage).Error; err != nil {
-				log.Printf("could not save history: %v", err)
-				return c.Status(http.StatusInternalServerError).SendString(fmt.Sprintf("Error saving history: %v", err))
-			}
-			//save map
-			mapHistoryMessage := MapHistoryMessage{
-				HistoryId: ask.HistoryId,
-				MessageId: historyMessage.ID,
-			}
-			if err := h.db.Create(&mapHistoryMessage).Error; err != nil {
-				log.Printf("could not save map: %v", err)
-				return c.Status(http.StatusInternalServerError).SendString(fmt.Sprintf("Error saving map: %v", err))
-			}
-			return c.SendString("https://img2.pic.in.th/pic/.-650x900.png")
+		if strings.Contains(strings.ToLower(question), fee) {
+			return true
 		}
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second) // Increased timeout to 5 seconds
-	defer cancel()
+	return false
+}
 
-	// Create the request message.
-	req := &AiRequest{ // Assuming your request message is named AskRequest
-		Question: ask.Question,
+// readAsk parses a JSON or multipart/form-data question. A multipart request
+// may carry a photo in the "image" field; it is checked and sent along with
+// the question, but only stored by attachImage once the question is answered.
+func readAsk(c *fiber.Ctx) (Ask, HistoryMessage, *AiRequest, error) {
+	ask := Ask{}
+	if err := c.BodyParser(&ask); err != nil {
+		return ask, HistoryMessage{}, nil, fiber.NewError(http.StatusBadRequest, "Invalid request body")
+	}
+	if ask.Question == "" {
+		return ask, HistoryMessage{}, nil, fiber.NewError(http.StatusBadRequest, "Query parameter 'question' is required")
+	}
+	if ask.Lang == "" {
+		ask.Lang = c.Query("lang")
 	}
+	if ask.Lang != "" && ask.Lang != "th" && ask.Lang != "en" {
+		return ask, HistoryMessage{}, nil, fiber.NewError(http.StatusBadRequest, "Parameter 'lang' must be th or en")
+	}
+	if !ask.Debug {
+		ask.Debug = c.Query("debug") == "true"
+	}
+	level, _ := c.Locals("level").(int)
+	message := HistoryMessage{Question: ask.Question}
+	req := &AiRequest{Question: ask.Question, Lang: ask.Lang, Debug: ask.Debug && level >= models.LevelAdmin}
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		return ask, message, req, nil
+	}
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		// a multipart question without a photo
+		return ask, message, req, nil
+	}
+	data, mimeType, err := readImage(fileHeader)
+	if err != nil {
+		return ask, message, nil, err
+	}
+	req.Image = data
+	req.ImageMimeType = mimeType
+	return ask, message, req, nil
+}
 
-	// Call the gRPC method.
-	r, err := h.client.Ask(ctx, req) // Assuming your method is named Ask
+// attachImage stores the photo of an answered question and references it
+// from message so the history can show it.
+func attachImage(message *HistoryMessage, req *AiRequest) error {
+	if len(req.Image) == 0 {
+		return nil
+	}
+	name, err := saveImage(req.Image, req.ImageMimeType)
 	if err != nil {
-		log.Printf("could not ask: %v", err)
-		return c.Status(http.StatusInternalServerError).SendString(fmt.Sprintf("Error calling gRPC: %v", err))
+		log.Printf("could not save image: %v", err)
+		return fiber.NewError(http.StatusInternalServerError, fmt.Sprintf("Error saving image: %v", err))
 	}
-	//save message
-	historyMessage := HistoryMessage{
-		Question:  ask.Question,
-		Answer:    r.GetAnswer(),
-		CreatedAt: time.Now(),
+	message.Image = name
+	message.ImageMimeType = req.ImageMimeType
+	return nil
+}
+
+// loadHistory returns the last turns of the history as messages for the AI,
+// oldest first. At most ask.history.max_turns question/answer pairs are sent,
+// the AI service trims them further to its token budget. A history of
+// another user is refused with 403 Forbidden.
+func (h *askHandler) loadHistory(historyId int, userID string) ([]*Message, error) {
+	if historyId == 0 {
+		return nil, nil
+	}
+	if err := h.checkOwner(historyId, userID); err != nil {
+		return nil, err
+	}
+	maxTurns := viper.GetInt("ask.history.max_turns")
+	if maxTurns <= 0 {
+		maxTurns = 10
+	}
+	var historyMessages []MapHistoryMessage
+	if err := h.db.Preload("Message").Where("history_id = ?", historyId).Order("id desc").Limit(maxTurns).Find(&historyMessages).Error; err != nil {
+		return nil, fmt.Errorf("Error getting history messages: %v", err)
+	}
+	messages := make([]*Message, 0, 2*len(historyMessages))
+	for i := len(historyMessages) - 1; i >= 0; i-- {
+		m := historyMessages[i].Message
+		messages = append(messages,
+			&Message{Role: "user", Content: m.Question},
+			&Message{Role: "assistant", Content: m.Answer},
+		)
 	}
+	return messages, nil
+}
+
+// checkOwner refuses a history of another user with 403 Forbidden.
+func (h *askHandler) checkOwner(historyId int, userID string) error {
+	var owners int64
+	if err := h.db.Model(&MapUserHistory{}).Where("history_id = ? AND main_user_id = ?", historyId, userID).Count(&owners).Error; err != nil {
+		return fmt.Errorf("Error getting history: %v", err)
+	}
+	if owners == 0 {
+		return fiber.NewError(http.StatusForbidden, "History belongs to another user")
+	}
+	return nil
+}
+
+// sendError writes an error from readAsk or saveHistory as plain text.
+func sendError(c *fiber.Ctx, err error) error {
+	if e, ok := err.(*fiber.Error); ok {
+		return c.Status(e.Code).SendString(e.Message)
+	}
+	return c.Status(http.StatusInternalServerError).SendString(err.Error())
+}
+
+// saveHistory stores a question/answer pair and links it to the history.
+// An answer that was handed off opens a ticket for staff.
+func (h *askHandler) saveHistory(historyId int, userID string, historyMessage HistoryMessage) error {
+	//save message
+	historyMessage.CreatedAt = time.Now()
 	if err := h.db.Create(&historyMessage).Error; err != nil {
 		log.Printf("could not save history: %v", err)
-		return c.Status(http.StatusInternalServerError).SendString(fmt.Sprintf("Error saving history: %v", err))
+		return fmt.Errorf("Error saving history: %v", err)
 	}
 	//save map
 	mapHistoryMessage := MapHistoryMessage{
-		HistoryId: ask.HistoryId,
+		HistoryId: historyId,
 		MessageId: historyMessage.ID,
 	}
 	if err := h.db.Create(&mapHistoryMessage).Error; err != nil {
 		log.Printf("could not save map: %v", err)
-		return c.Status(http.StatusInternalServerError).SendString(fmt.Sprintf("Error saving map: %v", err))
+		return fmt.Errorf("Error saving map: %v", err)
+	}
+	if historyMessage.Escalation == escalationHandoff {
+		return h.openTicket(userID, historyMessage)
+	}
+	return nil
+}
+
+// @Summary Ask a question
+// @Description Ask a question to the AI service.
+// @Description The X-Answer-Source header is faq when the answer is the curated one of the official FAQ, model when generated and search when looked up on the web.
+// @Description Send multipart/form-data with question, history_id and an optional image file (jpeg, png, webp or gif) to ask about a photo.
+// @Description Set lang (th or en, in the body or the query) to be answered in that language only; otherwise the answer has both, labelled "ภาษาไทย:" and "English:".
+// @Tags Ask
+// @Accept json,mpfd
+// @Produce json
+// @Param question body Ask true "Question to ask"
+// @Header 200 {string} X-Answer-Source "faq, model, search or handoff"
+// @Header 200 {string} X-Knowledge-Version "version of the knowledge base, e.g. question.json@3"
+// @Header 200 {string} X-Prompt-Version "version of the prompt templates, e.g. v1 or v1/claude"
+// @Header 200 {string} X-Answer-Outcome "answered, unknown or refused"
+// @Header 200 {string} X-Answer-Escalation "search, provider or handoff when the AI did not know"
+// @Header 200 {string} X-Debug-Attempts "JSON list of the model calls made, with debug for admins"
+// @Router /api/v1/ask/ [post]
+// @Security ApiKeyAuth
+func (h *askHandler) Ask(c *fiber.Ctx) error {
+	ask, message, req, err := readAsk(c)
+	if err != nil {
+		return sendError(c, err)
+	}
+	userID := c.Locals("user_id").(string)
+	if req.History, err = h.loadHistory(ask.HistoryId, userID); err != nil {
+		return sendError(c, err)
+	}
+	if isFeeQuestion(ask.Question) {
+		message.Answer = feeTableURL
+		if err := h.saveHistory(ask.HistoryId, userID, message); err != nil {
+			return sendError(c, err)
+		}
+		return c.SendString(feeTableURL)
+	}
+	// the deadline is passed on to the AI service, which keeps within it
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Call the gRPC method.
+	r, err := h.client.Ask(ctx, req) // Assuming your method is named Ask
+	if err != nil {
+		log.Printf("could not ask: %v", err)
+		if status.Code(err) == codes.DeadlineExceeded {
+			return c.Status(http.StatusGatewayTimeout).SendString("The AI service did not answer in time")
+		}
+		return c.Status(http.StatusInternalServerError).SendString(fmt.Sprintf("Error calling gRPC: %v", err))
+	}
+	message.Answer = r.GetAnswer()
+	message.AnswerTh = r.GetAnswerTh()
+	message.AnswerEn = r.GetAnswerEn()
+	message.Source = r.GetSource()
+	message.KnowledgeVersion = r.GetKnowledgeVersion()
+	message.PromptVersion = r.GetPromptVersion()
+	message.Outcome = r.GetOutcome()
+	message.Escalation = r.GetEscalation()
+	message.Citations = toHistoryCitations(r.GetCitations())
+	if err := attachImage(&message, req); err != nil {
+		return sendError(c, err)
+	}
+	if err := h.saveHistory(ask.HistoryId, userID, message); err != nil {
+		return sendError(c, err)
+	}
+	c.Set("X-Answer-Source", r.GetSource())
+	c.Set("X-Knowledge-Version", r.GetKnowledgeVersion())
+	c.Set("X-Prompt-Version", r.GetPromptVersion())
+	c.Set("X-Answer-Outcome", r.GetOutcome())
+	c.Set("X-Answer-Escalation", r.GetEscalation())
+	if attempts := r.GetAttempts(); len(attempts) > 0 {
+		if data, err := json.Marshal(attempts); err == nil {
+			c.Set("X-Debug-Attempts", string(data))
+		}
 	}
 	return c.SendString(r.GetAnswer()) // Assuming your response message has a field named Answer
 }
 
+// @Summary Ask a question and stream the answer
+// @Description Ask a question to the AI service and receive the answer as Server-Sent Events.
+// @Description Every event carries an AiStreamResponse; the last one has done=true with the model, finish reason, source (faq, model, search or handoff), knowledge version, prompt version, outcome (answered, unknown or refused), escalation, citations and the answer split into answer_th and answer_en; with debug, admins also get the model calls made as attempts.
+// @Description Accepts the same JSON or multipart/form-data body as /api/v1/ask/.
+// @Tags Ask
+// @Accept json,mpfd
+// @Produce text/event-stream
+// @Param question body Ask true "Question to ask"
+// @Router /api/v1/ask/stream [post]
+// @Security ApiKeyAuth
+func (h *askHandler) AskStream(c *fiber.Ctx) error {
+	ask, message, req, err := readAsk(c)
+	if err != nil {
+		return sendError(c, err)
+	}
+	userID := c.Locals("user_id").(string)
+	if req.History, err = h.loadHistory(ask.HistoryId, userID); err != nil {
+		return sendError(c, err)
+	}
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	if isFeeQuestion(ask.Question) {
+		message.Answer = feeTableURL
+		if err := h.saveHistory(ask.HistoryId, userID, message); err != nil {
+			return sendError(c, err)
+		}
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			writeEvent(w, &AiStreamResponse{Chunk: feeTableURL})
+			writeEvent(w, &AiStreamResponse{Done: true})
+		})
+		return nil
+	}
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+
+		stream, err := h.client.AskStream(ctx, req)
+		if err != nil {
+			log.Printf("could not ask: %v", err)
+			writeEvent(w, fiber.Map{"error": fmt.Sprintf("Error calling gRPC: %v", err)})
+			return
+		}
+		var answer strings.Builder
+		for {
+			r, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Printf("could not receive: %v", err)
+				writeEvent(w, fiber.Map{"error": fmt.Sprintf("Error calling gRPC: %v", err)})
+				return
+			}
+			answer.WriteString(r.GetChunk())
+			if r.GetDone() {
+				message.Source = r.GetSource()
+				message.KnowledgeVersion = r.GetKnowledgeVersion()
+				message.PromptVersion = r.GetPromptVersion()
+				message.Outcome = r.GetOutcome()
+				message.Escalation = r.GetEscalation()
+				message.Citations = toHistoryCitations(r.GetCitations())
+				message.AnswerTh = r.GetAnswerTh()
+				message.AnswerEn = r.GetAnswerEn()
+			}
+			if err := writeEvent(w, r); err != nil {
+				// client went away, stop the upstream call
+				return
+			}
+		}
+		message.Answer = answer.String()
+		if err := attachImage(&message, req); err != nil {
+			writeEvent(w, fiber.Map{"error": err.Error()})
+			return
+		}
+		if err := h.saveHistory(ask.HistoryId, userID, message); err != nil {
+			writeEvent(w, fiber.Map{"error": err.Error()})
+		}
+	})
+	return nil
+}
+
+// writeEvent writes v as a single Server-Sent Event and flushes it to the client.
+func writeEvent(w *bufio.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
 // @Summary Create a history
 // @Description Create a history
 // @Tags History
@@ -262,7 +558,7 @@ func (h *askHandler) DeleteHistory(c *fiber.Ctx) error {
 }
 
 // @Summary Get history messages by history ID
-// @Description Get history messages by history ID
+// @Description Get history messages by history ID, every answer with the citations of the official sources it was given from. Only the owner of the history and admins may read it.
 // @Tags History
 // @Accept json
 // @Produce json
@@ -270,9 +566,18 @@ func (h *askHandler) DeleteHistory(c *fiber.Ctx) error {
 // @Router /api/v1/ask/history/messages/{id} [get]
 // @Security ApiKeyAuth
 func (h *askHandler) GetHistoryMessageByHistoryID(c *fiber.Ctx) error {
-	historyID := c.Params("id")
+	historyID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).SendString("Invalid history ID")
+	}
+	userID, _ := c.Locals("user_id").(string)
+	if level, _ := c.Locals("level").(int); level < models.LevelAdmin {
+		if err := h.checkOwner(historyID, userID); err != nil {
+			return sendError(c, err)
+		}
+	}
 	var historyMessages []MapHistoryMessage
-	if err := h.db.Preload(clause.Associations).Where("history_id = ?", historyID).Find(&historyMessages).Error; err != nil {
+	if err := h.db.Preload(clause.Associations).Preload("Message.Citations").Where("history_id = ?", historyID).Find(&historyMessages).Error; err != nil {
 		log.Printf("could not get history messages: %v", err)
 		return c.Status(http.StatusInternalServerError).SendString(fmt.Sprintf("Error getting history messages: %v", err))
 	}
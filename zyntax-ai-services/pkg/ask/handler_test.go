@@ -0,0 +1,120 @@
+package ask
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestHandler(t *testing.T) *askHandler {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&MainUser{}, &History{}, &MapUserHistory{}, &HistoryMessage{}, &HistoryCitation{}, &MapHistoryMessage{}))
+	return &askHandler{db: db}
+}
+
+// getAs sends a GET of path to handler on route as a logged in user, see
+// ReqAuthHandler, and returns the status.
+func getAs(t *testing.T, route, path string, handler fiber.Handler, userID string, level int) int {
+	app := fiber.New()
+	app.Get(route, func(c *fiber.Ctx) error {
+		c.Locals("user_id", userID)
+		c.Locals("level", level)
+		return c.Next()
+	}, handler)
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	assert.NoError(t, err)
+	return resp.StatusCode
+}
+
+func TestLoadHistory(t *testing.T) {
+	h := newTestHandler(t)
+	history := History{PlaceHolder: "fees"}
+	assert.NoError(t, h.db.Create(&history).Error)
+	assert.NoError(t, h.db.Create(&MapUserHistory{MainUserID: "somchai", HistoryID: history.ID}).Error)
+	for i := 1; i <= 3; i++ {
+		message := HistoryMessage{Question: fmt.Sprintf("q%d", i), Answer: fmt.Sprintf("a%d", i)}
+		assert.NoError(t, h.saveHistory(history.ID, "somchai", message))
+	}
+
+	t.Run("newest turns, oldest first", func(t *testing.T) {
+		viper.Set("ask.history.max_turns", 2)
+		defer viper.Set("ask.history.max_turns", nil)
+		messages, err := h.loadHistory(history.ID, "somchai")
+		assert.NoError(t, err)
+		assert.Equal(t, []*Message{
+			{Role: "user", Content: "q2"}, {Role: "assistant", Content: "a2"},
+			{Role: "user", Content: "q3"}, {Role: "assistant", Content: "a3"},
+		}, messages)
+	})
+	t.Run("no history", func(t *testing.T) {
+		messages, err := h.loadHistory(0, "somchai")
+		assert.NoError(t, err)
+		assert.Empty(t, messages)
+	})
+	t.Run("history of another user", func(t *testing.T) {
+		_, err := h.loadHistory(history.ID, "somsri")
+		var fiberErr *fiber.Error
+		assert.ErrorAs(t, err, &fiberErr)
+		assert.Equal(t, http.StatusForbidden, fiberErr.Code)
+	})
+}
+
+func TestWriteEvent(t *testing.T) {
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	assert.NoError(t, writeEvent(w, &AiStreamResponse{Chunk: "สวัสดี"}))
+	assert.NoError(t, writeEvent(w, &AiStreamResponse{Done: true, Model: "gemini-2.0-flash"}))
+	assert.Equal(t, "data: {\"chunk\":\"สวัสดี\"}\n\ndata: {\"done\":true,\"model\":\"gemini-2.0-flash\"}\n\n", out.String())
+}
+
+func TestHistoryCitations(t *testing.T) {
+	citations := toHistoryCitations([]*Citation{
+		{Source: "CLI-1.pdf", Page: 3, Title: "CLI-1"},
+		{Source: "question.json", EntryId: "leave"},
+		{Url: "https://reg.kku.ac.th", Title: "reg.kku.ac.th"},
+	})
+	assert.Equal(t, []HistoryCitation{
+		{Source: "CLI-1.pdf", Page: 3, Title: "CLI-1"},
+		{Source: "question.json", EntryID: "leave"},
+		{URL: "https://reg.kku.ac.th", Title: "reg.kku.ac.th"},
+	}, citations)
+	assert.Empty(t, toHistoryCitations(nil))
+
+	t.Run("kept with the history", func(t *testing.T) {
+		h := newTestHandler(t)
+		assert.NoError(t, h.saveHistory(1, "somchai", HistoryMessage{Question: "q", Answer: "a", Citations: citations}))
+		var saved []MapHistoryMessage
+		assert.NoError(t, h.db.Preload("Message.Citations").Where("history_id = ?", 1).Find(&saved).Error)
+		assert.Len(t, saved, 1)
+		assert.Len(t, saved[0].Message.Citations, 3)
+		assert.Equal(t, "CLI-1.pdf", saved[0].Message.Citations[0].Source)
+	})
+}
+
+func TestGetHistoryMessages(t *testing.T) {
+	h := newTestHandler(t)
+	history := History{PlaceHolder: "fees"}
+	assert.NoError(t, h.db.Create(&history).Error)
+	assert.NoError(t, h.db.Create(&MapUserHistory{MainUserID: "somchai", HistoryID: history.ID}).Error)
+	assert.NoError(t, h.saveHistory(history.ID, "somchai", HistoryMessage{Question: "q", Answer: "a"}))
+	get := func(path, userID string, level int) int {
+		return getAs(t, "/history/messages/:id", path, h.GetHistoryMessageByHistoryID, userID, level)
+	}
+	path := fmt.Sprintf("/history/messages/%d", history.ID)
+
+	assert.Equal(t, http.StatusOK, get(path, "somchai", models.LevelUser))
+	assert.Equal(t, http.StatusForbidden, get(path, "somsri", models.LevelUser))
+	assert.Equal(t, http.StatusOK, get(path, "admin", models.LevelAdmin))
+	assert.Equal(t, http.StatusBadRequest, get("/history/messages/x", "somchai", models.LevelUser))
+}
@@ -0,0 +1,121 @@
+package ask
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Escalation of an answer the AI did not know that is passed to staff, see
+// AiResponse.
+const escalationHandoff = "handoff"
+
+// Statuses of a handoff ticket.
+const (
+	HandoffOpen   = "open"
+	HandoffClosed = "closed"
+)
+
+// HandoffTicket is a question the AI could not answer, waiting for staff.
+type HandoffTicket struct {
+	ID               int            `json:"id" gorm:"primaryKey;autoIncrement"`
+	HistoryMessageID int            `json:"history_message_id" gorm:"index"`
+	HistoryMessage   HistoryMessage `json:"history_message" gorm:"foreignKey:HistoryMessageID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	// MainUserID asked the question.
+	MainUserID string `json:"main_user_id" gorm:"index"`
+	Status     string `json:"status" gorm:"index" enums:"open,closed"`
+	// Reply is the answer of staff, ResolvedBy the ID of who gave it.
+	Reply      string     `json:"reply,omitempty"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// HandoffReply is the answer of staff to a handoff ticket.
+type HandoffReply struct {
+	Reply string `json:"reply"`
+}
+
+// openTicket hands the question of message to staff.
+func (h *askHandler) openTicket(userID string, message HistoryMessage) error {
+	ticket := HandoffTicket{
+		HistoryMessageID: message.ID,
+		MainUserID:       userID,
+		Status:           HandoffOpen,
+		CreatedAt:        time.Now(),
+	}
+	if err := h.db.Create(&ticket).Error; err != nil {
+		log.Printf("could not save handoff ticket: %v", err)
+		return fmt.Errorf("Error saving handoff ticket: %v", err)
+	}
+	return nil
+}
+
+// @Summary Get handoff tickets
+// @Description Get the questions the AI did not know and passed to staff, newest first
+// @Tags Handoff
+// @Accept json
+// @Produce json
+// @Param status query string false "open or closed, all when empty" Enums(open, closed)
+// @Success 200 {array} HandoffTicket
+// @Router /api/v1/ask/handoffs [get]
+// @Security ApiKeyAuth
+func (h *askHandler) GetHandoffs(c *fiber.Ctx) error {
+	query := h.db.Preload("HistoryMessage").Order("id desc")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var tickets []HandoffTicket
+	if err := query.Find(&tickets).Error; err != nil {
+		log.Printf("could not get handoff tickets: %v", err)
+		return c.Status(http.StatusInternalServerError).SendString(fmt.Sprintf("Error getting handoff tickets: %v", err))
+	}
+	return c.JSON(tickets)
+}
+
+// @Summary Reply to a handoff ticket
+// @Description Close a handoff ticket with the answer of staff, which replaces the answer in the history of the user
+// @Tags Handoff
+// @Accept json
+// @Produce json
+// @Param id path string true "Ticket ID"
+// @Param reply body HandoffReply true "Answer of staff"
+// @Success 200 {object} HandoffTicket
+// @Router /api/v1/ask/handoffs/{id} [put]
+// @Security ApiKeyAuth
+func (h *askHandler) ResolveHandoff(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).SendString("Invalid ticket ID")
+	}
+	reply := HandoffReply{}
+	if err := c.BodyParser(&reply); err != nil || reply.Reply == "" {
+		return c.Status(http.StatusBadRequest).SendString("Invalid request body")
+	}
+	ticket := HandoffTicket{}
+	if err := h.db.First(&ticket, id).Error; err != nil {
+		return c.Status(http.StatusNotFound).SendString("Ticket not found")
+	}
+	now := time.Now()
+	ticket.Status = HandoffClosed
+	ticket.Reply = reply.Reply
+	ticket.ResolvedBy = c.Locals("user_id").(string)
+	ticket.ResolvedAt = &now
+	if err := h.db.Save(&ticket).Error; err != nil {
+		log.Printf("could not update handoff ticket: %v", err)
+		return c.Status(http.StatusInternalServerError).SendString(fmt.Sprintf("Error updating handoff ticket: %v", err))
+	}
+	if err := h.db.Model(&HistoryMessage{}).Where("id = ?", ticket.HistoryMessageID).Updates(map[string]interface{}{
+		"answer":    reply.Reply,
+		"answer_th": "",
+		"answer_en": "",
+	}).Error; err != nil {
+		log.Printf("could not update history message: %v", err)
+		return c.Status(http.StatusInternalServerError).SendString(fmt.Sprintf("Error updating history message: %v", err))
+	}
+	return c.JSON(ticket)
+}
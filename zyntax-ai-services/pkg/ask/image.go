@@ -0,0 +1,122 @@
+package ask
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/spf13/viper"
+)
+
+// allowedImageTypes maps the image types the AI can read to the extension
+// they are stored with.
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// imageName matches the names given by saveImage.
+var imageName = regexp.MustCompile(`^[0-9a-f]{64}\.(jpg|png|webp|gif)$`)
+
+func imageDir() string {
+	if dir := viper.GetString("ask.image.path"); dir != "" {
+		return dir
+	}
+	return "./uploads/images"
+}
+
+func maxImageSize() int64 {
+	if size := viper.GetInt64("ask.image.max_size"); size > 0 {
+		return size
+	}
+	return 4 * 1024 * 1024
+}
+
+// readImage reads an uploaded photo. The type is sniffed from the content,
+// the header sent by the client is not trusted.
+func readImage(fileHeader *multipart.FileHeader) (data []byte, mimeType string, err error) {
+	if fileHeader.Size > maxImageSize() {
+		return nil, "", fiber.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("image is larger than %d bytes", maxImageSize()))
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, "", fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+	defer file.Close()
+	data, err = io.ReadAll(io.LimitReader(file, maxImageSize()+1))
+	if err != nil {
+		return nil, "", fiber.NewError(http.StatusBadRequest, err.Error())
+	}
+	if int64(len(data)) > maxImageSize() {
+		return nil, "", fiber.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("image is larger than %d bytes", maxImageSize()))
+	}
+	mimeType = http.DetectContentType(data)
+	if _, ok := allowedImageTypes[mimeType]; !ok {
+		return nil, "", fiber.NewError(http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported image type %s", mimeType))
+	}
+	return data, mimeType, nil
+}
+
+// saveImage stores the photo under the hash of its content and returns the
+// name it can be fetched by.
+func saveImage(data []byte, mimeType string) (string, error) {
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:]) + allowedImageTypes[mimeType]
+	if err := os.MkdirAll(imageDir(), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(imageDir(), name), data, 0644); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// @Summary Get an image of a question
+// @Description Get a photo that was sent with a question, by the name stored in the history message. Only the owner of the history and admins may read it.
+// @Tags Ask
+// @Produce image/jpeg,image/png,image/webp,image/gif
+// @Param name path string true "Image name"
+// @Router /api/v1/ask/images/{name} [get]
+// @Security ApiKeyAuth
+func (h *askHandler) GetImage(c *fiber.Ctx) error {
+	name := c.Params("name")
+	if !imageName.MatchString(name) {
+		return c.Status(http.StatusBadRequest).SendString("Invalid image name")
+	}
+	if level, _ := c.Locals("level").(int); level < models.LevelAdmin {
+		userID, _ := c.Locals("user_id").(string)
+		if err := h.checkImageOwner(name, userID); err != nil {
+			return sendError(c, err)
+		}
+	}
+	return c.SendFile(filepath.Join(imageDir(), name))
+}
+
+// checkImageOwner refuses with 403 Forbidden a photo that was not sent in a
+// history of userID. The same photo sent by several users has one name, any
+// of them may read it.
+func (h *askHandler) checkImageOwner(name, userID string) error {
+	var owners int64
+	err := h.db.Model(&MapUserHistory{}).
+		Joins("JOIN map_history_messages ON map_history_messages.history_id = map_user_histories.history_id").
+		Joins("JOIN history_messages ON history_messages.id = map_history_messages.message_id").
+		Where("history_messages.image = ? AND map_user_histories.main_user_id = ?", name, userID).
+		Count(&owners).Error
+	if err != nil {
+		return fmt.Errorf("Error getting image: %v", err)
+	}
+	if owners == 0 {
+		return fiber.NewError(http.StatusForbidden, "Image belongs to another user")
+	}
+	return nil
+}
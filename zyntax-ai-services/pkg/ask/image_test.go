@@ -0,0 +1,184 @@
+package ask
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+var png = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+
+// multipartBody builds a form with fields and, when image is not nil, an
+// "image" file.
+func multipartBody(t *testing.T, fields map[string]string, image []byte) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for k, v := range fields {
+		assert.NoError(t, w.WriteField(k, v))
+	}
+	if image != nil {
+		part, err := w.CreateFormFile("image", "photo.png")
+		assert.NoError(t, err)
+		_, err = part.Write(image)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	return body, w.FormDataContentType()
+}
+
+func fileHeader(t *testing.T, image []byte) *multipart.FileHeader {
+	body, contentType := multipartBody(t, nil, image)
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", contentType)
+	assert.NoError(t, req.ParseMultipartForm(1<<20))
+	return req.MultipartForm.File["image"][0]
+}
+
+func useImageDir(t *testing.T) string {
+	dir := t.TempDir()
+	viper.Set("ask.image.path", dir)
+	t.Cleanup(func() { viper.Set("ask.image.path", nil) })
+	return dir
+}
+
+func TestReadImage(t *testing.T) {
+	t.Run("png", func(t *testing.T) {
+		data, mimeType, err := readImage(fileHeader(t, png))
+		assert.NoError(t, err)
+		assert.Equal(t, png, data)
+		assert.Equal(t, "image/png", mimeType)
+	})
+	t.Run("not an image", func(t *testing.T) {
+		_, _, err := readImage(fileHeader(t, []byte("%PDF-1.4 not a photo")))
+		var fiberErr *fiber.Error
+		assert.ErrorAs(t, err, &fiberErr)
+		assert.Equal(t, http.StatusUnsupportedMediaType, fiberErr.Code)
+	})
+	t.Run("too large", func(t *testing.T) {
+		viper.Set("ask.image.max_size", 32)
+		defer viper.Set("ask.image.max_size", nil)
+		_, _, err := readImage(fileHeader(t, png))
+		var fiberErr *fiber.Error
+		assert.ErrorAs(t, err, &fiberErr)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, fiberErr.Code)
+	})
+}
+
+func TestSaveImage(t *testing.T) {
+	dir := useImageDir(t)
+	name, err := saveImage(png, "image/png")
+	assert.NoError(t, err)
+	assert.Regexp(t, imageName, name)
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	assert.NoError(t, err)
+	assert.Equal(t, png, data)
+
+	// the same photo is stored once
+	again, err := saveImage(png, "image/png")
+	assert.NoError(t, err)
+	assert.Equal(t, name, again)
+}
+
+func TestReadAsk(t *testing.T) {
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		ask, message, req, err := readAsk(c)
+		if err != nil {
+			return sendError(c, err)
+		}
+		return c.JSON(fiber.Map{"ask": ask, "message": message, "image": req.Image, "mime_type": req.ImageMimeType})
+	})
+	type result struct {
+		Ask      Ask
+		Message  HistoryMessage
+		Image    []byte
+		MimeType string `json:"mime_type"`
+	}
+	send := func(body io.Reader, contentType string) (*http.Response, result) {
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var res result
+		if resp.StatusCode == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		}
+		return resp, res
+	}
+
+	t.Run("json", func(t *testing.T) {
+		resp, res := send(bytes.NewBufferString(`{"question":"When does the office open?","history_id":3,"lang":"en"}`), fiber.MIMEApplicationJSON)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, Ask{Question: "When does the office open?", HistoryId: 3, Lang: "en"}, res.Ask)
+		assert.Empty(t, res.Image)
+	})
+	t.Run("multipart with an image", func(t *testing.T) {
+		dir := useImageDir(t)
+		body, contentType := multipartBody(t, map[string]string{"question": "What does this notice say?", "history_id": "3"}, png)
+		resp, res := send(body, contentType)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 3, res.Ask.HistoryId)
+		assert.Equal(t, png, res.Image)
+		assert.Equal(t, "image/png", res.MimeType)
+		// stored only once the question is answered
+		assert.Empty(t, res.Message.Image)
+		entries, _ := os.ReadDir(dir)
+		assert.Empty(t, entries)
+	})
+	t.Run("multipart without an image", func(t *testing.T) {
+		body, contentType := multipartBody(t, map[string]string{"question": "hello"}, nil)
+		resp, res := send(body, contentType)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "hello", res.Ask.Question)
+		assert.Empty(t, res.Image)
+	})
+	t.Run("invalid question with an image", func(t *testing.T) {
+		dir := useImageDir(t)
+		body, contentType := multipartBody(t, map[string]string{"question": "hello", "lang": "fr"}, png)
+		resp, _ := send(body, contentType)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		entries, _ := os.ReadDir(dir)
+		assert.Empty(t, entries)
+	})
+}
+
+func TestAttachImage(t *testing.T) {
+	dir := useImageDir(t)
+	message := HistoryMessage{}
+	assert.NoError(t, attachImage(&message, &AiRequest{}))
+	assert.Empty(t, message.Image)
+
+	assert.NoError(t, attachImage(&message, &AiRequest{Image: png, ImageMimeType: "image/png"}))
+	assert.Equal(t, "image/png", message.ImageMimeType)
+	assert.FileExists(t, filepath.Join(dir, message.Image))
+}
+
+func TestGetImage(t *testing.T) {
+	useImageDir(t)
+	h := newTestHandler(t)
+	history := History{PlaceHolder: "notice"}
+	assert.NoError(t, h.db.Create(&history).Error)
+	assert.NoError(t, h.db.Create(&MapUserHistory{MainUserID: "somchai", HistoryID: history.ID}).Error)
+	message := HistoryMessage{Question: "What does this notice say?", Answer: "a"}
+	assert.NoError(t, attachImage(&message, &AiRequest{Image: png, ImageMimeType: "image/png"}))
+	assert.NoError(t, h.saveHistory(history.ID, "somchai", message))
+	get := func(name, userID string, level int) int {
+		return getAs(t, "/images/:name", "/images/"+name, h.GetImage, userID, level)
+	}
+
+	assert.Equal(t, http.StatusOK, get(message.Image, "somchai", models.LevelUser))
+	assert.Equal(t, http.StatusForbidden, get(message.Image, "somsri", models.LevelUser))
+	assert.Equal(t, http.StatusOK, get(message.Image, "admin", models.LevelAdmin))
+	assert.Equal(t, http.StatusBadRequest, get("photo.png", "somchai", models.LevelUser))
+}
@@ -65,7 +65,7 @@ func (h *AuthHandler) Login() fiber.Handler {
 		claims := token.Claims.(*jwt.RegisteredClaims)
 		claims.Subject = login.ID
 		claims.Issuer = c.Hostname()
-		claims.Audience = []string{fmt.Sprintf("%v:%v", "highest permission", "5")}
+		claims.Audience = []string{fmt.Sprintf("%v:%v", models.Role(login.Level), login.Level)}
 		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour * 24))
 		signToken, err := token.SignedString(h.JwtSignKey)
 		if err != nil {
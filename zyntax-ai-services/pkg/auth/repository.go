@@ -19,10 +19,31 @@ func (r *authRepository) Login(user models.MainUser) (*models.MainUser, error) {
 		err := fiber.NewError(fiber.StatusServiceUnavailable, "Database server has gone away")
 		return nil, err
 	}
-	err := r.First(&user, "user_id = ?", user.ID)
+	var found models.MainUser
+	err := r.First(&found, "id = ?", user.ID)
 	if err.Error != nil {
-		r.Create(&user)
+		if user.Level == 0 {
+			user.Level = models.LevelUser
+		}
+		if err := r.Create(&user).Error; err != nil {
+			return nil, err
+		}
 		return &user, nil
 	}
+	// the level follows auth.admins on every login, so removing a user from
+	// it demotes them
+	if user.Level != 0 && user.Level != found.Level {
+		found.Level = user.Level
+		if err := r.Model(&found).Update("level", found.Level).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &found, nil
+}
+func (r *authRepository) GetUser(id string) (*models.MainUser, error) {
+	var user models.MainUser
+	if err := r.First(&user, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
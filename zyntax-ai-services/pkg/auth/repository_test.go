@@ -0,0 +1,69 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/auth"
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestLogin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.MainUser{}))
+	repo := auth.NewAuthRepository(db)
+	user := models.MainUser{ID: "somchai", Email: "somchai@kku.ac.th", Level: models.LevelUser}
+
+	t.Run("new user", func(t *testing.T) {
+		login, err := repo.Login(user)
+		assert.NoError(t, err)
+		assert.Equal(t, models.LevelUser, login.Level)
+	})
+	t.Run("promoted", func(t *testing.T) {
+		user.Level = models.LevelAdmin
+		login, err := repo.Login(user)
+		assert.NoError(t, err)
+		assert.Equal(t, models.LevelAdmin, login.Level)
+	})
+	t.Run("demoted", func(t *testing.T) {
+		user.Level = models.LevelUser
+		login, err := repo.Login(user)
+		assert.NoError(t, err)
+		assert.Equal(t, models.LevelUser, login.Level)
+
+		var stored models.MainUser
+		assert.NoError(t, db.First(&stored, "id = ?", user.ID).Error)
+		assert.Equal(t, models.LevelUser, stored.Level)
+	})
+}
+
+func TestLevel(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.MainUser{}))
+	service := auth.NewAuthService(auth.NewAuthRepository(db))
+	assert.NoError(t, db.Create(&models.MainUser{ID: "somchai", Email: "somchai@kku.ac.th", Level: models.LevelAdmin}).Error)
+	assert.NoError(t, db.Create(&models.MainUser{ID: "somsri", Level: models.LevelUser}).Error)
+
+	viper.Set("auth.admins", []string{"somchai@kku.ac.th"})
+	defer viper.Set("auth.admins", nil)
+	level, err := service.Level("somchai")
+	assert.NoError(t, err)
+	assert.Equal(t, models.LevelAdmin, level)
+	level, err = service.Level("somsri")
+	assert.NoError(t, err)
+	assert.Equal(t, models.LevelUser, level)
+
+	// removed from auth.admins, before logging in again
+	viper.Set("auth.admins", nil)
+	level, err = service.Level("somchai")
+	assert.NoError(t, err)
+	assert.Equal(t, models.LevelUser, level)
+
+	_, err = service.Level("nobody")
+	assert.Error(t, err)
+}
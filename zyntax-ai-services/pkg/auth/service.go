@@ -109,6 +109,10 @@ func (s *authService) Login(code string) (*models.MainUser, error) {
 		NameTh: fullname,
 		NameEn: fullnameEng,
 		Email:  userDetails["sso_mail"].(string),
+		Level:  models.LevelUser,
+	}
+	if isAdmin(user) {
+		user.Level = models.LevelAdmin
 	}
 	login, err := s.authRepository.Login(user)
 	if err != nil {
@@ -119,5 +123,30 @@ func (s *authService) Login(code string) (*models.MainUser, error) {
 		NameTh: login.NameTh,
 		NameEn: login.NameEn,
 		Email:  login.Email,
+		Level:  login.Level,
 	}, nil
 }
+
+// Level is the stored level of the user, lowered to LevelUser when
+// auth.admins no longer lists an admin: the stored level only follows
+// auth.admins on the next login.
+func (s *authService) Level(userID string) (int, error) {
+	user, err := s.authRepository.GetUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	if user.Level >= models.LevelAdmin && !isAdmin(*user) {
+		return models.LevelUser, nil
+	}
+	return user.Level, nil
+}
+
+// isAdmin reports whether auth.admins lists the user by ID or e-mail.
+func isAdmin(user models.MainUser) bool {
+	for _, admin := range viper.GetStringSlice("auth.admins") {
+		if strings.EqualFold(admin, user.ID) || strings.EqualFold(admin, user.Email) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,8 +6,12 @@ import (
 
 type AuthRepository interface {
 	Login(models.MainUser) (*models.MainUser, error)
+	GetUser(id string) (*models.MainUser, error)
 }
 
 type AuthService interface {
 	Login(string) (*models.MainUser, error)
+	// Level is the level the user has now, which a token signed before a
+	// demotion does not show.
+	Level(userID string) (int, error)
 }
@@ -1,6 +1,11 @@
 package domain
 
-import "github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
+import (
+	"context"
+	"io"
+
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
+)
 
 type FileRepository interface {
 	CreateFile(file models.File) (models.File, error)
@@ -8,11 +13,20 @@ type FileRepository interface {
 	GetFiles() ([]models.File, error)
 	DeleteFile(id string) error
 	UpdateFile(file models.File) (models.File, error)
+	CreateAudit(audit models.FileAudit) error
+	GetAudits(fileID string) ([]models.FileAudit, error)
 }
 type FileService interface {
 	CreateFile(file models.File) (models.File, error)
 	GetFile(id string) (models.File, error)
 	GetFiles() ([]models.File, error)
-	DeleteFile(id string) error
-	UpdateFile(file models.File) (models.File, error)
+	// DeleteFile and UpdateFile record userID in the audit trail.
+	DeleteFile(id, userID string) error
+	UpdateFile(file models.File, userID string) (models.File, error)
+	GetAudits(id string) ([]models.FileAudit, error)
+	// Upload checks the type and size of the contents, stores them under a
+	// generated key and records them as a file.
+	Upload(ctx context.Context, name string, body io.Reader, size int64, uploadedBy string) (models.File, error)
+	// Open returns the file and its contents, which the caller must close.
+	Open(ctx context.Context, id string) (models.File, io.ReadCloser, error)
 }
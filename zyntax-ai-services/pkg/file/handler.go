@@ -1,14 +1,18 @@
 package file
 
 import (
-	"os"
+	"errors"
+	"fmt"
+	"net/url"
 	"strconv"
 
 	"github.com/Zentrix-Software-Hive/zyntax-ai-services/internal/handlers"
 	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/domain"
 	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/storage"
 	"github.com/gofiber/fiber/v2"
 	helpers "github.com/zercle/gofiber-helpers"
+	"gorm.io/gorm"
 )
 
 type fileHandler struct {
@@ -16,15 +20,18 @@ type fileHandler struct {
 	service domain.FileService
 }
 
-func NewFileHandler(router fiber.Router, service domain.FileService) {
+func NewFileHandler(router fiber.Router, auth *handlers.RouterResources, service domain.FileService) {
 	handler := &fileHandler{
 		service: service,
 	}
-	router.Get("/", handler.GetFiles())
-	router.Get("/:id", handler.GetFile())
-	router.Post("", handler.CreateFile())
-	router.Put("/:id", handler.UpdateFile())
-	router.Delete("/:id", handler.DeleteFile())
+	// the files are the knowledge the AI answers from, only admins change them
+	router.Get("/", auth.ReqAuthHandler(models.LevelUser), handler.GetFiles())
+	router.Get("/:id", auth.ReqAuthHandler(models.LevelUser), handler.GetFile())
+	router.Get("/:id/content", auth.ReqAuthHandler(models.LevelUser), handler.GetFileContent())
+	router.Get("/:id/audits", auth.ReqAuthHandler(models.LevelAdmin), handler.GetFileAudits())
+	router.Post("", auth.ReqAuthHandler(models.LevelAdmin), handler.CreateFile())
+	router.Put("/:id", auth.ReqAuthHandler(models.LevelAdmin), handler.UpdateFile())
+	router.Delete("/:id", auth.ReqAuthHandler(models.LevelAdmin), handler.DeleteFile())
 }
 
 // @Summary Get all files
@@ -67,8 +74,64 @@ func (h *fileHandler) GetFile() fiber.Handler {
 	}
 }
 
+// @Summary Download the contents of a file
+// @Description Download the contents of a file
+// @Tags files
+// @Produce octet-stream
+// @Param id path string true "File ID"
+// @Router /api/v1/files/{id}/content [get]
+// @Security ApiKeyAuth
+func (h *fileHandler) GetFileContent() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		file, contents, err := h.service.Open(c.UserContext(), id)
+		if err != nil {
+			status := fiber.StatusInternalServerError
+			if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, storage.ErrNotFound) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		// files recorded before the contents were stored have no type or size
+		if file.MimeType == "" {
+			file.MimeType = fiber.MIMEOctetStream
+		}
+		size := int(file.Size)
+		if size == 0 {
+			size = -1
+		}
+		c.Set(fiber.HeaderContentType, file.MimeType)
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(file.Name)))
+		// fiber closes contents once it is sent
+		return c.SendStream(contents, size)
+	}
+}
+
+// @Summary Get the audit trail of a file
+// @Description Who uploaded, updated or deleted the file, newest first
+// @Tags files
+// @Accept json
+// @Produce json
+// @Param id path string true "File ID"
+// @Success 200 {array} models.FileAudit
+// @Router /api/v1/files/{id}/audits [get]
+// @Security ApiKeyAuth
+func (h *fileHandler) GetFileAudits() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		audits, err := h.service.GetAudits(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(audits)
+	}
+}
+
 // @Summary Create a new file
-// @Description Create a new file
+// @Description Upload a knowledge file: .json, .txt, .md (2MB) or .pdf, .docx (10MB). It is stored under a generated name. JSON that does not parse, or a knowledge base without a version or categories, is refused with 400.
 // @Tags files
 // @Accept multipart/form-data
 // @Produce json
@@ -88,18 +151,26 @@ func (h *fileHandler) CreateFile() fiber.Handler {
 				},
 			})
 		}
-		err = os.WriteFile("../ai/assets/"+file.Filename, []byte(""), 0644)
+		body, err := file.Open()
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
-		var fileType models.File
-		fileType.Name = file.Filename
-		fileType.Path = "../ai/assets/" + file.Filename
-		createdFile, err := h.service.CreateFile(fileType)
+		defer body.Close()
+		userID, _ := c.Locals("user_id").(string)
+		createdFile, err := h.service.Upload(c.UserContext(), file.Filename, body, file.Size, userID)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			status := fiber.StatusInternalServerError
+			switch {
+			case errors.Is(err, ErrFileType):
+				status = fiber.StatusUnsupportedMediaType
+			case errors.Is(err, ErrFileTooLarge):
+				status = fiber.StatusRequestEntityTooLarge
+			case errors.Is(err, ErrFileContents):
+				status = fiber.StatusBadRequest
+			}
+			return c.Status(status).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
@@ -108,7 +179,7 @@ func (h *fileHandler) CreateFile() fiber.Handler {
 }
 
 // @Summary Update a file
-// @Description Update a file
+// @Description Rename a file. Only Name is read, the stored contents and their Path cannot be changed.
 // @Tags files
 // @Accept json
 // @Produce json
@@ -132,9 +203,14 @@ func (h *fileHandler) UpdateFile() fiber.Handler {
 			})
 		}
 		file.ID = idInt
-		updatedFile, err := h.service.UpdateFile(file)
+		userID, _ := c.Locals("user_id").(string)
+		updatedFile, err := h.service.UpdateFile(file, userID)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			status := fiber.StatusInternalServerError
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				status = fiber.StatusNotFound
+			}
+			return c.Status(status).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
@@ -153,7 +229,8 @@ func (h *fileHandler) UpdateFile() fiber.Handler {
 func (h *fileHandler) DeleteFile() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id := c.Params("id")
-		if err := h.service.DeleteFile(id); err != nil {
+		userID, _ := c.Locals("user_id").(string)
+		if err := h.service.DeleteFile(id, userID); err != nil {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": err.Error(),
 			})
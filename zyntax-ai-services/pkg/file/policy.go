@@ -0,0 +1,97 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	// ErrFileType is returned for an upload the knowledge base does not take.
+	ErrFileType = errors.New("file type not allowed")
+	// ErrFileTooLarge is returned for an upload over the cap of its type.
+	ErrFileTooLarge = errors.New("file too large")
+	// ErrFileContents is returned for an upload the ai service cannot read.
+	ErrFileContents = errors.New("invalid file contents")
+)
+
+// fileType is what an upload of one extension may be.
+type fileType struct {
+	mimeType string
+	// maxSize is overridden by file.max_size.<extension without dot>.
+	maxSize int64
+	// magic, when set, is how the contents must start.
+	magic []byte
+	text  bool
+	// check, when set, is run on the whole contents before they are stored.
+	check func(data []byte) error
+}
+
+// allowedTypes are the files the ai service can index.
+var allowedTypes = map[string]fileType{
+	".json": {mimeType: "application/json", maxSize: 2 << 20, text: true, check: checkJSON},
+	".txt":  {mimeType: "text/plain; charset=utf-8", maxSize: 2 << 20, text: true},
+	".md":   {mimeType: "text/markdown; charset=utf-8", maxSize: 2 << 20, text: true},
+	".pdf":  {mimeType: "application/pdf", maxSize: 10 << 20, magic: []byte("%PDF-")},
+	".docx": {mimeType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", maxSize: 10 << 20, magic: []byte("PK\x03\x04")},
+}
+
+// checkUpload returns the extension and type of an upload of name, or why
+// it is refused. head is the start of its contents.
+func checkUpload(name string, size int64, head []byte) (string, fileType, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	t, ok := allowedTypes[ext]
+	if !ok {
+		return "", fileType{}, fmt.Errorf("%w: %q, allowed are .json, .txt, .md, .pdf and .docx", ErrFileType, ext)
+	}
+	maxSize := t.maxSize
+	if configured := viper.GetInt64("file.max_size." + strings.TrimPrefix(ext, ".")); configured > 0 {
+		maxSize = configured
+	}
+	if size > maxSize {
+		return "", fileType{}, fmt.Errorf("%w: %s files are limited to %d bytes", ErrFileTooLarge, ext, maxSize)
+	}
+	if size <= 0 {
+		return "", fileType{}, fmt.Errorf("%w: the file is empty", ErrFileType)
+	}
+	if t.magic != nil && !bytes.HasPrefix(head, t.magic) {
+		return "", fileType{}, fmt.Errorf("%w: the contents are not %s", ErrFileType, ext)
+	}
+	if t.text && bytes.IndexByte(head, 0) >= 0 {
+		return "", fileType{}, fmt.Errorf("%w: the contents are not text", ErrFileType)
+	}
+	return ext, t, nil
+}
+
+// checkJSON rejects what the ai service would fail to load as a whole: JSON
+// that does not parse, and a knowledge base without a version or categories.
+// Problems of single entries are left to the ai service, which drops them.
+func checkJSON(data []byte) error {
+	if !json.Valid(data) {
+		return fmt.Errorf("%w: not valid JSON", ErrFileContents)
+	}
+	var probe map[string]json.RawMessage
+	if json.Unmarshal(data, &probe) != nil || probe["categories"] == nil {
+		// a legacy FAQ or other JSON, indexed as it is
+		return nil
+	}
+	var base struct {
+		Version    int               `json:"version"`
+		Categories []json.RawMessage `json:"categories"`
+	}
+	if err := json.Unmarshal(data, &base); err != nil {
+		return fmt.Errorf("%w: %v", ErrFileContents, err)
+	}
+	if base.Version < 1 {
+		return fmt.Errorf("%w: knowledge base version must be 1 or more", ErrFileContents)
+	}
+	if len(base.Categories) == 0 {
+		return fmt.Errorf("%w: knowledge base has no categories", ErrFileContents)
+	}
+	return nil
+}
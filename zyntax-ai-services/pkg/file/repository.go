@@ -21,7 +21,7 @@ func (r *fileRepository) CreateFile(file models.File) (models.File, error) {
 }
 func (r *fileRepository) GetFile(id string) (models.File, error) {
 	var file models.File
-	if err := r.First(&file, id).Error; err != nil {
+	if err := r.First(&file, "id = ?", id).Error; err != nil {
 		return models.File{}, err
 	}
 	return file, nil
@@ -34,7 +34,7 @@ func (r *fileRepository) GetFiles() ([]models.File, error) {
 	return files, nil
 }
 func (r *fileRepository) DeleteFile(id string) error {
-	if err := r.Delete(&models.File{}, id).Error; err != nil {
+	if err := r.Delete(&models.File{}, "id = ?", id).Error; err != nil {
 		return err
 	}
 	return nil
@@ -45,3 +45,13 @@ func (r *fileRepository) UpdateFile(file models.File) (models.File, error) {
 	}
 	return file, nil
 }
+func (r *fileRepository) CreateAudit(audit models.FileAudit) error {
+	return r.Create(&audit).Error
+}
+func (r *fileRepository) GetAudits(fileID string) ([]models.FileAudit, error) {
+	var audits []models.FileAudit
+	if err := r.Where("file_id = ?", fileID).Order("created_at desc").Find(&audits).Error; err != nil {
+		return nil, err
+	}
+	return audits, nil
+}
@@ -0,0 +1,43 @@
+package file_test
+
+import (
+	"testing"
+
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/file"
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestFileID(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.File{}))
+	repo := file.NewFileRepository(db)
+	for _, name := range []string{"faq.md", "fees.md"} {
+		_, err := repo.CreateFile(models.File{Name: name, Path: name})
+		assert.NoError(t, err)
+	}
+
+	t.Run("get", func(t *testing.T) {
+		f, err := repo.GetFile("2")
+		assert.NoError(t, err)
+		assert.Equal(t, "fees.md", f.Name)
+	})
+	t.Run("id is a value, not SQL", func(t *testing.T) {
+		_, err := repo.GetFile("0 OR 1=1")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		assert.NoError(t, repo.DeleteFile("0 OR 1=1"))
+		files, err := repo.GetFiles()
+		assert.NoError(t, err)
+		assert.Len(t, files, 2)
+	})
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, repo.DeleteFile("1"))
+		files, err := repo.GetFiles()
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+	})
+}
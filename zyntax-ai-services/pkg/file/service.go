@@ -1,17 +1,31 @@
 package file
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
 	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/domain"
 	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/storage"
 )
 
 type fileService struct {
 	repository domain.FileRepository
+	storage    storage.Storage
 }
 
-func NewFileService(repository domain.FileRepository) domain.FileService {
+func NewFileService(repository domain.FileRepository, storage storage.Storage) domain.FileService {
 	return &fileService{
 		repository: repository,
+		storage:    storage,
 	}
 }
 func (fs *fileService) CreateFile(file models.File) (models.File, error) {
@@ -27,13 +41,124 @@ func (fs *fileService) GetFiles() ([]models.File, error) {
 	}
 	return files, nil
 }
-func (fs *fileService) DeleteFile(id string) error {
-	return fs.repository.DeleteFile(id)
+func (fs *fileService) DeleteFile(id, userID string) error {
+	file, err := fs.repository.GetFile(id)
+	if err != nil {
+		return err
+	}
+	if err := fs.repository.DeleteFile(id); err != nil {
+		return err
+	}
+	if err := fs.audit(file, models.FileDeleted, userID); err != nil {
+		return err
+	}
+	return fs.storage.Delete(context.Background(), storageKey(file))
 }
-func (fs *fileService) UpdateFile(file models.File) (models.File, error) {
-	updated, err := fs.repository.UpdateFile(file)
+
+// UpdateFile renames a file. Where the contents are stored and what they
+// are cannot be changed through it.
+func (fs *fileService) UpdateFile(file models.File, userID string) (models.File, error) {
+	existing, err := fs.repository.GetFile(strconv.Itoa(file.ID))
 	if err != nil {
 		return models.File{}, err
 	}
+	existing.Name = displayName(file.Name)
+	if existing.Name == "" {
+		return models.File{}, errors.New("file name is required")
+	}
+	updated, err := fs.repository.UpdateFile(existing)
+	if err != nil {
+		return models.File{}, err
+	}
+	if err := fs.audit(updated, models.FileUpdated, userID); err != nil {
+		return models.File{}, err
+	}
 	return updated, nil
 }
+func (fs *fileService) GetAudits(id string) ([]models.FileAudit, error) {
+	return fs.repository.GetAudits(id)
+}
+
+// Upload stores the contents under a generated key; name is only shown.
+func (fs *fileService) Upload(ctx context.Context, name string, body io.Reader, size int64, uploadedBy string) (models.File, error) {
+	reader := bufio.NewReader(body)
+	head, _ := reader.Peek(512)
+	ext, t, err := checkUpload(name, size, head)
+	if err != nil {
+		return models.File{}, err
+	}
+	if t.check != nil {
+		// small text, checkUpload capped the size
+		data, err := io.ReadAll(io.LimitReader(reader, size))
+		if err != nil {
+			return models.File{}, err
+		}
+		if err := t.check(data); err != nil {
+			return models.File{}, err
+		}
+		reader = bufio.NewReader(bytes.NewReader(data))
+	}
+	key, err := storage.NewKey(name, ext)
+	if err != nil {
+		return models.File{}, err
+	}
+	file := models.File{
+		Name:       displayName(name),
+		Path:       key,
+		Size:       size,
+		MimeType:   t.mimeType,
+		UploadedBy: uploadedBy,
+	}
+	hash := sha256.New()
+	if err := fs.storage.Put(ctx, file.Path, io.TeeReader(reader, hash), size, file.MimeType); err != nil {
+		return models.File{}, err
+	}
+	file.Hash = hex.EncodeToString(hash.Sum(nil))
+	created, err := fs.repository.CreateFile(file)
+	if err != nil {
+		fs.storage.Delete(ctx, file.Path)
+		return models.File{}, err
+	}
+	if err := fs.audit(created, models.FileUploaded, uploadedBy); err != nil {
+		return models.File{}, err
+	}
+	return created, nil
+}
+func (fs *fileService) Open(ctx context.Context, id string) (models.File, io.ReadCloser, error) {
+	file, err := fs.repository.GetFile(id)
+	if err != nil {
+		return models.File{}, nil, err
+	}
+	contents, err := fs.storage.Get(ctx, storageKey(file))
+	if err != nil {
+		return models.File{}, nil, err
+	}
+	return file, contents, nil
+}
+
+func (fs *fileService) audit(file models.File, action, userID string) error {
+	return fs.repository.CreateAudit(models.FileAudit{
+		FileID:   file.ID,
+		FileName: file.Name,
+		Action:   action,
+		UserID:   userID,
+	})
+}
+
+// displayName drops any directories a client put in a file name.
+func displayName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "." || name == "/" {
+		return ""
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+	return name
+}
+
+// storageKey also covers files recorded before the storage backend, whose
+// Path was "../ai/assets/<name>".
+func storageKey(file models.File) string {
+	return filepath.Base(file.Path)
+}
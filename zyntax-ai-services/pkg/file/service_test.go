@@ -0,0 +1,69 @@
+package file_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/domain"
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/file"
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/models"
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestService stores files in a new directory under a temporary one.
+func newTestService(t *testing.T) (domain.FileService, string) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&models.File{}, &models.FileAudit{}))
+	dir := filepath.Join(t.TempDir(), "assets")
+	local, err := storage.NewLocal(dir)
+	assert.NoError(t, err)
+	return file.NewFileService(file.NewFileRepository(db), local), dir
+}
+
+func TestUploadName(t *testing.T) {
+	service, dir := newTestService(t)
+	contents := "Parking is behind building 50."
+	created, err := service.Upload(context.Background(), "../../escape.md", strings.NewReader(contents), int64(len(contents)), "1")
+	assert.NoError(t, err)
+	// the client's name is shown, never written to
+	assert.Equal(t, "escape.md", created.Name)
+	assert.Equal(t, filepath.Base(created.Path), created.Path)
+	assert.FileExists(t, filepath.Join(dir, created.Path))
+	assert.NoFileExists(t, filepath.Join(dir, "../../escape.md"))
+}
+
+func TestUploadJSON(t *testing.T) {
+	service, dir := newTestService(t)
+	upload := func(contents string) error {
+		_, err := service.Upload(context.Background(), "kb.json", strings.NewReader(contents), int64(len(contents)), "1")
+		return err
+	}
+
+	for name, contents := range map[string]string{
+		"not JSON":      `{"version": 1, "categories": [`,
+		"no version":    `{"categories": [{"id": "fees"}]}`,
+		"no categories": `{"version": 1, "categories": []}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, upload(contents), file.ErrFileContents)
+		})
+	}
+	t.Run("nothing stored", func(t *testing.T) {
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+	t.Run("knowledge base", func(t *testing.T) {
+		assert.NoError(t, upload(`{"version": 1, "categories": [{"id": "fees"}]}`))
+	})
+	t.Run("legacy FAQ", func(t *testing.T) {
+		assert.NoError(t, upload(`{"data": [{"question": "ค่าเทอม", "answer": "ตามประกาศ"}]}`))
+	})
+}
@@ -2,6 +2,22 @@ package models
 
 import "github.com/golang-jwt/jwt/v4"
 
+// Permission levels, carried in the JWT audience as "<role>:<level>" and
+// checked by ReqAuthHandler. A route open to a level is open to every level
+// above it.
+const (
+	LevelUser  = 1
+	LevelAdmin = 5
+)
+
+// Role names a permission level in the JWT audience.
+func Role(level int) string {
+	if level >= LevelAdmin {
+		return "admin"
+	}
+	return "user"
+}
+
 type TokenClaims struct {
 	ID string `json:"id"`
 	jwt.RegisteredClaims
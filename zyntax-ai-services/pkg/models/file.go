@@ -1,7 +1,34 @@
 package models
 
+import "time"
+
 type File struct {
 	ID   int    `gorm:"primaryKey;autoIncrement"`
 	Name string `gorm:"type:varchar(255);not null"`
+	// Path is the key of the contents in the storage backend.
 	Path string `gorm:"type:varchar(255);not null"`
+	// Hash is the hex SHA-256 of the contents.
+	Hash       string `gorm:"type:char(64)"`
+	Size       int64
+	MimeType   string `gorm:"type:varchar(255)"`
+	UploadedBy string `gorm:"type:varchar(255)"`
+	CreatedAt  time.Time
+}
+
+// File audit actions.
+const (
+	FileUploaded = "upload"
+	FileUpdated  = "update"
+	FileDeleted  = "delete"
+)
+
+// FileAudit records who changed which knowledge file. It keeps the name so
+// the record still reads after the file is deleted.
+type FileAudit struct {
+	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	FileID    int       `json:"file_id" gorm:"index"`
+	FileName  string    `json:"file_name" gorm:"type:varchar(255)"`
+	Action    string    `json:"action" gorm:"type:varchar(16);not null"`
+	UserID    string    `json:"user_id" gorm:"type:varchar(255);not null"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -5,4 +5,6 @@ type MainUser struct {
 	NameTh string `json:"name_th"`
 	NameEn string `json:"name_en"`
 	Email  string `json:"email"`
+	// Level is the permission level put in the JWT audience, see LevelUser.
+	Level int `json:"level" gorm:"not null;default:1"`
 }
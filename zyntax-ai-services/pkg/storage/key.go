@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// maxStemLen keeps generated keys well inside a varchar(255).
+const maxStemLen = 100
+
+// NewKey generates the key to store an upload under. The client's name only
+// lends it a readable stem, e.g. "../../คู่มือ 2567.pdf" becomes
+// "คู่มือ-2567-3f9a1c2e.pdf", so keys never collide or leave the storage.
+func NewKey(name, ext string) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	stem := Stem(name)
+	if stem == "" {
+		stem = "file"
+	}
+	return stem + "-" + hex.EncodeToString(suffix) + ext, nil
+}
+
+// Stem keeps the letters, marks and digits of the base name of name without
+// its extension, joining the runs between them with dashes.
+func Stem(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	var b strings.Builder
+	dash := false
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) || r == '_' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	stem := []rune(b.String())
+	if len(stem) > maxStemLen {
+		stem = stem[:maxStemLen]
+	}
+	return strings.TrimRight(string(stem), "-")
+}
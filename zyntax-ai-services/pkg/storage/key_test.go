@@ -0,0 +1,32 @@
+package storage_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKey(t *testing.T) {
+	t.Run("traversal", func(t *testing.T) {
+		key, err := storage.NewKey("../../etc/passwd", ".txt")
+		assert.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(`^passwd-[0-9a-f]{8}\.txt$`), key)
+	})
+	t.Run("windows path", func(t *testing.T) {
+		key, err := storage.NewKey(`C:\Users\admin\คู่มือ 2567.pdf`, ".pdf")
+		assert.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(`^คู่มือ-2567-[0-9a-f]{8}\.pdf$`), key)
+	})
+	t.Run("no readable stem", func(t *testing.T) {
+		key, err := storage.NewKey("...", ".md")
+		assert.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(`^file-[0-9a-f]{8}\.md$`), key)
+	})
+	t.Run("unique", func(t *testing.T) {
+		a, _ := storage.NewKey("a.json", ".json")
+		b, _ := storage.NewKey("a.json", ".json")
+		assert.NotEqual(t, a, b)
+	})
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ErrNotFound is returned by Get for a key that does not exist.
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage keeps uploaded file contents by key.
+type Storage interface {
+	// Put stores size bytes of body under key, replacing what was there.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewFromConfig builds the backend named by storage.driver: local (default)
+// keeps files in storage.local.path, s3 in a bucket of any S3-compatible API.
+//
+// The ai service indexes its ./assets directory, so the local default points
+// there; with s3 the bucket has to be synced to the ai service separately.
+func NewFromConfig() (Storage, error) {
+	switch driver := viper.GetString("storage.driver"); driver {
+	case "", "local":
+		dir := viper.GetString("storage.local.path")
+		if dir == "" {
+			dir = "../ai/assets"
+		}
+		return NewLocal(dir)
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:  viper.GetString("storage.s3.endpoint"),
+			Region:    viper.GetString("storage.s3.region"),
+			Bucket:    viper.GetString("storage.s3.bucket"),
+			AccessKey: viper.GetString("storage.s3.access_key"),
+			SecretKey: viper.GetString("storage.s3.secret_key"),
+			HTTPClient: &http.Client{
+				Timeout: 5 * time.Minute,
+			},
+		})
+	default:
+		return nil, fmt.Errorf("unsupported storage driver %q", driver)
+	}
+}
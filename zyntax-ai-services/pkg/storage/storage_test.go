@@ -0,0 +1,87 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Zentrix-Software-Hive/zyntax-ai-services/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is a stand-in for an S3-compatible API that keeps objects in memory.
+func fakeS3(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	objects := map[string]string{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-key/"))
+		assert.Equal(t, "UNSIGNED-PAYLOAD", r.Header.Get("x-amz-content-sha256"))
+		assert.NotEmpty(t, r.Header.Get("x-amz-date"))
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = string(body)
+		case http.MethodGet:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, body)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+func testStorage(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	t.Run("put and get", func(t *testing.T) {
+		content := "สวัสดี knowledge"
+		assert.NoError(t, s.Put(ctx, "คู่มือ (1).txt", strings.NewReader(content), int64(len(content)), "text/plain"))
+		r, err := s.Get(ctx, "คู่มือ (1).txt")
+		if assert.NoError(t, err) {
+			defer r.Close()
+			got, _ := io.ReadAll(r)
+			assert.Equal(t, content, string(got))
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, s.Delete(ctx, "คู่มือ (1).txt"))
+		_, err := s.Get(ctx, "คู่มือ (1).txt")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+	t.Run("missing", func(t *testing.T) {
+		_, err := s.Get(ctx, "missing.pdf")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
+
+func TestLocal(t *testing.T) {
+	s, err := storage.NewLocal(t.TempDir())
+	assert.NoError(t, err)
+	testStorage(t, s)
+	t.Run("key outside the directory", func(t *testing.T) {
+		assert.Error(t, s.Put(context.Background(), "../escape.txt", strings.NewReader("x"), 1, ""))
+	})
+}
+
+func TestS3(t *testing.T) {
+	server := fakeS3(t)
+	defer server.Close()
+	s, err := storage.NewS3(storage.S3Config{
+		Endpoint:  server.URL,
+		Bucket:    "knowledge",
+		AccessKey: "test-key",
+		SecretKey: "test-secret",
+	})
+	assert.NoError(t, err)
+	testStorage(t, s)
+}